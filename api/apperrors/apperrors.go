@@ -0,0 +1,150 @@
+// Package apperrors defines the errors the API reports to its clients. Each kind
+// maps to one HTTP status; any other error is an internal error whose message is
+// not shown to clients.
+package apperrors
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type Kind int
+
+const (
+	Internal Kind = iota
+	NotFound
+	Conflict
+	Validation
+	Unauthorized
+	Forbidden
+)
+
+var kinds = map[Kind]struct {
+	status int
+	slug   string
+}{
+	Internal:     {http.StatusInternalServerError, "internal"},
+	NotFound:     {http.StatusNotFound, "not-found"},
+	Conflict:     {http.StatusConflict, "conflict"},
+	Validation:   {http.StatusUnprocessableEntity, "validation"},
+	Unauthorized: {http.StatusUnauthorized, "unauthorized"},
+	Forbidden:    {http.StatusForbidden, "forbidden"},
+}
+
+// Status is the HTTP status of the kind
+func (k Kind) Status() int {
+	return kinds[k].status
+}
+
+// Slug names the kind in problem types, such as not-found
+func (k Kind) Slug() string {
+	return kinds[k].slug
+}
+
+// FieldError is one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error the client can act upon
+type Error struct {
+	Kind    Kind
+	Message string
+	// Fields are the invalid fields of a validation error
+	Fields []FieldError
+	// Err is the underlying error, never shown to clients
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors of the same kind and message, so that errors.Is works with
+// sentinel errors built by the functions below
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Message == e.Message
+}
+
+func newError(kind Kind, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+func NewNotFound(message string) error     { return newError(NotFound, message) }
+func NewConflict(message string) error     { return newError(Conflict, message) }
+func NewUnauthorized(message string) error { return newError(Unauthorized, message) }
+func NewForbidden(message string) error    { return newError(Forbidden, message) }
+
+// NewValidation is a validation error without a field, such as an unreadable body
+func NewValidation(message string) error { return newError(Validation, message) }
+
+// Wrap gives err a kind and a message for clients, err stays reachable through
+// errors.Is and errors.As
+func Wrap(kind Kind, message string, err error) error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// KindOf is the kind of err, Internal for errors of no kind
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// Fields are the invalid fields of a validation error
+func Fields(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
+
+// Validator collects every invalid field of a request before reporting them at once
+type Validator struct {
+	fields []FieldError
+}
+
+// Add records an invalid field
+func (v *Validator) Add(field, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: message})
+}
+
+// Check records an invalid field when ok is false
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.Add(field, message)
+	}
+}
+
+// Has tells whether the field was already found invalid
+func (v *Validator) Has(field string) bool {
+	for _, f := range v.fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Err is the validation error listing every invalid field, or nil. Its message
+// joins the messages of the fields.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	messages := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		messages[i] = f.Message
+	}
+	return &Error{Kind: Validation, Message: strings.Join(messages, "; "), Fields: v.fields}
+}
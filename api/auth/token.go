@@ -1,11 +1,9 @@
 package auth
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -13,28 +11,48 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+var (
+	secret   []byte
+	tokenTTL = time.Hour
+)
+
+// Configure sets the key tokens are signed with and how long they stay valid, it
+// has to be called before any token is created or checked
+func Configure(apiSecret string, ttl time.Duration) {
+	secret = []byte(apiSecret)
+	tokenTTL = ttl
+}
+
 func CreateToken(user_id uint32) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("Token secret not configured")
+	}
 	claims := jwt.MapClaims{}
 	claims["authorized"] = true
 	claims["user_id"] = user_id
-	claims["exp"] = time.Now().Add(time.Hour * 1).Unix() // Token expires after 1 hour
+	claims["exp"] = time.Now().Add(tokenTTL).Unix()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("API_SECRET")))
+	return token.SignedString(secret)
+}
+
+func signingKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("Token secret not configured")
+	}
+	return secret, nil
 }
 
 func TokenValid(r *http.Request) error {
 	tokenString := ExtractToken(r)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
+	token, err := jwt.Parse(tokenString, signingKey)
 	if err != nil {
 		return err
 	}
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		Pretty(claims)
+	if !token.Valid {
+		return errors.New("Invalid token")
 	}
 	return nil
 }
@@ -54,12 +72,7 @@ func ExtractToken(r *http.Request) string {
 
 func ExtractTokenID(r *http.Request) (uint32, error) {
 	tokenString := ExtractToken(r)
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("API_SECRET")), nil
-	})
+	token, err := jwt.Parse(tokenString, signingKey)
 	if err != nil {
 		return 0, err
 	}
@@ -73,13 +86,3 @@ func ExtractTokenID(r *http.Request) (uint32, error) {
 	}
 	return 0, nil
 }
-
-// Pretty display the claims nicely in the terminal
-func Pretty(data interface{}) {
-	b, err := json.MarshalIndent(data, "", " ")
-	if err != nil {
-		log.Println(err)
-		return
-	}
-	fmt.Println(string(b))
-}
@@ -0,0 +1,361 @@
+// Package config loads the typed configuration of the API.
+//
+// Values are read from, in increasing order of precedence: the defaults, an
+// optional YAML file, an optional .env file, the environment and the command line
+// flags. A .env file never overrides variables already set in the environment.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Port          int                 `yaml:"port"`
+	HTTP          HTTPConfig          `yaml:"http"`
+	DB            DBConfig            `yaml:"db"`
+	Auth          AuthConfig          `yaml:"auth"`
+	Log           LogConfig           `yaml:"log"`
+	Posts         PostsConfig         `yaml:"posts"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+	Tracing       TracingConfig       `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// DrainDelay is how long the readiness check fails before the server stops
+	// accepting connections, so load balancers stop sending requests first
+	DrainDelay     time.Duration `yaml:"drain_delay"`
+	MaxHeaderBytes int           `yaml:"max_header_bytes"`
+}
+
+type DBConfig struct {
+	Driver          string        `yaml:"driver"`
+	Host            string        `yaml:"host"`
+	Port            string        `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	Name            string        `yaml:"name"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	AutoMigrate     bool          `yaml:"auto_migrate"`
+}
+
+type AuthConfig struct {
+	Secret   string        `yaml:"secret"`
+	TokenTTL time.Duration `yaml:"token_ttl"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level"`
+	// SlowQuery is the duration above which a database statement is logged as a warning
+	SlowQuery time.Duration `yaml:"slow_query"`
+}
+
+type PostsConfig struct {
+	MaxContentLength int `yaml:"max_content_length"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp
+	Exporter string `yaml:"exporter"`
+	// File receives the spans of the stdout exporter instead of the standard output
+	File        string  `yaml:"file"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type NotificationsConfig struct {
+	// Retention is how long read notifications are kept before being pruned
+	Retention time.Duration `yaml:"retention"`
+}
+
+// Addr is the address the HTTP server listens on
+func (c *Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// Default is the configuration before any source is read
+func Default() Config {
+	return Config{
+		Port: 8082,
+		HTTP: HTTPConfig{
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+			DrainDelay:      5 * time.Second,
+			MaxHeaderBytes:  1 << 20,
+		},
+		DB: DBConfig{
+			Driver:          "postgres",
+			Host:            "localhost",
+			Port:            "5432",
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			AutoMigrate:     true,
+		},
+		Auth: AuthConfig{
+			TokenTTL: time.Hour,
+		},
+		Log: LogConfig{
+			Level:     "info",
+			SlowQuery: 200 * time.Millisecond,
+		},
+		Posts: PostsConfig{
+			MaxContentLength: 100000,
+		},
+		Notifications: NotificationsConfig{
+			Retention: 30 * 24 * time.Hour,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// setting is one value that can come from the environment and, unless secret, a flag
+type setting struct {
+	env    string
+	usage  string
+	secret bool
+	set    func(c *Config, value string) error
+}
+
+// flagName derives the flag of a setting from its environment variable, DB_HOST becomes -db-host
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+var settings = []setting{
+	{env: "API_PORT", usage: "port the HTTP server listens on", set: func(c *Config, v string) error { return setInt(&c.Port, v) }},
+	{env: "HTTP_READ_TIMEOUT", usage: "longest time to read a request", set: func(c *Config, v string) error { return setDuration(&c.HTTP.ReadTimeout, v) }},
+	{env: "HTTP_WRITE_TIMEOUT", usage: "longest time to write a response", set: func(c *Config, v string) error { return setDuration(&c.HTTP.WriteTimeout, v) }},
+	{env: "HTTP_IDLE_TIMEOUT", usage: "longest time a keep-alive connection stays idle", set: func(c *Config, v string) error { return setDuration(&c.HTTP.IdleTimeout, v) }},
+	{env: "HTTP_SHUTDOWN_TIMEOUT", usage: "longest time to drain connections on shutdown", set: func(c *Config, v string) error { return setDuration(&c.HTTP.ShutdownTimeout, v) }},
+	{env: "HTTP_DRAIN_DELAY", usage: "how long the readiness check fails before shutting down", set: func(c *Config, v string) error { return setDuration(&c.HTTP.DrainDelay, v) }},
+	{env: "HTTP_MAX_HEADER_BYTES", usage: "largest request header, in bytes", set: func(c *Config, v string) error { return setInt(&c.HTTP.MaxHeaderBytes, v) }},
+	{env: "DB_DRIVER", usage: "database driver, postgres, mysql or sqlite3", set: func(c *Config, v string) error { c.DB.Driver = v; return nil }},
+	{env: "DB_HOST", usage: "database host", set: func(c *Config, v string) error { c.DB.Host = v; return nil }},
+	{env: "DB_PORT", usage: "database port", set: func(c *Config, v string) error { c.DB.Port = v; return nil }},
+	{env: "DB_USER", usage: "database user", set: func(c *Config, v string) error { c.DB.User = v; return nil }},
+	{env: "DB_PASSWORD", secret: true, set: func(c *Config, v string) error { c.DB.Password = v; return nil }},
+	{env: "DB_NAME", usage: "database name, the file or :memory: for sqlite3", set: func(c *Config, v string) error { c.DB.Name = v; return nil }},
+	{env: "DB_MAX_OPEN_CONNS", usage: "most open database connections", set: func(c *Config, v string) error { return setInt(&c.DB.MaxOpenConns, v) }},
+	{env: "DB_MAX_IDLE_CONNS", usage: "most idle database connections", set: func(c *Config, v string) error { return setInt(&c.DB.MaxIdleConns, v) }},
+	{env: "DB_CONN_MAX_LIFETIME", usage: "longest time a database connection is reused", set: func(c *Config, v string) error { return setDuration(&c.DB.ConnMaxLifetime, v) }},
+	{env: "DB_AUTO_MIGRATE", usage: "apply pending migrations on startup", set: func(c *Config, v string) error { return setBool(&c.DB.AutoMigrate, v) }},
+	{env: "API_SECRET", secret: true, set: func(c *Config, v string) error { c.Auth.Secret = v; return nil }},
+	{env: "TOKEN_TTL", usage: "lifetime of the issued tokens", set: func(c *Config, v string) error { return setDuration(&c.Auth.TokenTTL, v) }},
+	{env: "LOG_LEVEL", usage: "debug, info, warn or error", set: func(c *Config, v string) error { c.Log.Level = strings.ToLower(v); return nil }},
+	{env: "LOG_SLOW_QUERY", usage: "database statements slower than this are logged as warnings, 0 disables", set: func(c *Config, v string) error { return setDuration(&c.Log.SlowQuery, v) }},
+	{env: "POST_MAX_CONTENT_LENGTH", usage: "longest post content, in characters", set: func(c *Config, v string) error { return setInt(&c.Posts.MaxContentLength, v) }},
+	{env: "TRACING_EXPORTER", usage: "where spans go: none, stdout or otlp", set: func(c *Config, v string) error { c.Tracing.Exporter = strings.ToLower(v); return nil }},
+	{env: "TRACING_FILE", usage: "file the stdout exporter writes to instead of the standard output", set: func(c *Config, v string) error { c.Tracing.File = v; return nil }},
+	{env: "TRACING_SAMPLE_RATIO", usage: "share of the new traces recorded, between 0 and 1", set: func(c *Config, v string) error { return setFloat(&c.Tracing.SampleRatio, v) }},
+	{env: "NOTIFICATION_RETENTION", usage: "how long read notifications are kept", set: func(c *Config, v string) error { return setDuration(&c.Notifications.Retention, v) }},
+}
+
+// Loader registers the configuration flags on a flag set and reads every source
+// once the flags are parsed
+type Loader struct {
+	flags      *flag.FlagSet
+	configFile *string
+	envFile    *string
+	values     map[string]*string
+}
+
+func NewLoader(flags *flag.FlagSet) *Loader {
+	l := &Loader{flags: flags, values: map[string]*string{}}
+	l.configFile = flags.String("config", "", "YAML configuration file, CONFIG_FILE in the environment")
+	l.envFile = flags.String("env-file", ".env", "file of environment variables, read when present")
+	for _, s := range settings {
+		if s.secret {
+			// Secrets on the command line end up in the process list
+			continue
+		}
+		l.values[s.env] = flags.String(s.flagName(), "", fmt.Sprintf("%s, %s in the environment", s.usage, s.env))
+	}
+	return l
+}
+
+// Load reads the configuration, the flag set must have been parsed
+func (l *Loader) Load() (*Config, error) {
+	c := Default()
+
+	// The .env file only fills the environment, which is applied over the YAML file below
+	if *l.envFile != "" {
+		err := godotenv.Load(*l.envFile)
+		// The file is optional, containers usually get a ready environment
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %v", *l.envFile, err)
+		}
+	}
+
+	configFile := *l.configFile
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+	if configFile != "" {
+		body, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		err = yaml.Unmarshal(body, &c)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", configFile, err)
+		}
+	}
+
+	for _, s := range settings {
+		v, ok := os.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		err := s.set(&c, strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.env, err)
+		}
+	}
+
+	set := map[string]bool{}
+	l.flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, s := range settings {
+		if s.secret || !set[s.flagName()] {
+			continue
+		}
+		err := s.set(&c, *l.values[s.env])
+		if err != nil {
+			return nil, fmt.Errorf("-%s: %v", s.flagName(), err)
+		}
+	}
+
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Validate reports every invalid or missing value at once
+func (c *Config) Validate() error {
+	problems := []string{}
+	if c.Port < 1 || c.Port > 65535 {
+		problems = append(problems, "API_PORT must be between 1 and 65535")
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"TOKEN_TTL", c.Auth.TokenTTL},
+		{"NOTIFICATION_RETENTION", c.Notifications.Retention},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			problems = append(problems, d.name+" must be positive")
+		}
+	}
+	if c.HTTP.DrainDelay < 0 {
+		problems = append(problems, "HTTP_DRAIN_DELAY must not be negative")
+	}
+	if c.HTTP.MaxHeaderBytes < 4096 {
+		problems = append(problems, "HTTP_MAX_HEADER_BYTES must be at least 4096")
+	}
+	switch c.DB.Driver {
+	case "postgres", "mysql", "sqlite3":
+	default:
+		problems = append(problems, fmt.Sprintf("DB_DRIVER %q is not supported, use postgres, mysql or sqlite3", c.DB.Driver))
+	}
+	if c.DB.Name == "" {
+		problems = append(problems, "DB_NAME is required")
+	}
+	if c.DB.MaxOpenConns < 1 {
+		problems = append(problems, "DB_MAX_OPEN_CONNS must be at least 1")
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		problems = append(problems, "DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		problems = append(problems, "DB_CONN_MAX_LIFETIME cannot be negative")
+	}
+	if c.Log.SlowQuery < 0 {
+		problems = append(problems, "LOG_SLOW_QUERY cannot be negative")
+	}
+	if c.Auth.Secret == "" {
+		problems = append(problems, "API_SECRET is required")
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("LOG_LEVEL %q is not one of debug, info, warn or error", c.Log.Level))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		problems = append(problems, fmt.Sprintf("TRACING_EXPORTER %q is not one of none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+	if c.Posts.MaxContentLength < 1 {
+		problems = append(problems, "POST_MAX_CONTENT_LENGTH must be at least 1")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func setInt(dst *int, v string) error {
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", v)
+	}
+	*dst = i
+	return nil
+}
+
+func setFloat(dst *float64, v string) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", v)
+	}
+	*dst = f
+	return nil
+}
+
+func setBool(dst *bool, v string) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", v)
+	}
+	*dst = b
+	return nil
+}
+
+func setDuration(dst *time.Duration, v string) error {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 30s or 5m", v)
+	}
+	*dst = d
+	return nil
+}
@@ -1,53 +1,125 @@
 package controllers
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jinzhu/gorm"
-	"github.com/planutim/postgres-copy/api/models"
-
-	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/planutim/postgres-copy/api/config"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/logging"
+	"github.com/planutim/postgres-copy/api/metrics"
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/api/tracing"
+	"github.com/planutim/postgres-copy/api/workers"
 )
 
 type Server struct {
-	DB     *gorm.DB
-	Router *mux.Router
+	DB       *gorm.DB
+	Router   *mux.Router
+	Workers  *workers.Group
+	Migrator *migrations.Migrator
+
+	draining atomic.Bool
 }
 
-func (server *Server) Initialize(DbDriver, DbUser, DbPassword, DbPort, DbName, DbHost string) {
-	var err error
-	if DbDriver == "mysql" {
-		DBURL := fmt.Sprintf("%s:%s:@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local", DbUser, DbPassword, DbHost, DbPort, DbName)
-		server.DB, err = gorm.Open(DbDriver, DBURL)
-		if err != nil {
-			fmt.Printf("Cannot connect to %s database", DbDriver)
-			log.Fatal("This is the error:", err)
-		} else {
-			fmt.Printf("We are connected to the %s database", DbDriver)
-		}
+func (server *Server) Initialize(cfg config.DBConfig, logs config.LogConfig) error {
+	err := server.Connect(cfg, logs)
+	if err != nil {
+		return err
 	}
-	if DbDriver == "postgres" {
-		DBURL := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", DbHost, DbPort, DbUser, DbName, DbPassword)
-		server.DB, err = gorm.Open(DbDriver, DBURL)
-		if err != nil {
-			fmt.Printf("Cannot connect to %s database", DbDriver)
-			log.Fatal("This is the error:", err)
-		} else {
-			fmt.Printf("We are connected to the %s database", DbDriver)
-		}
+	err = metrics.InstrumentDB(server.DB, cfg.Name)
+	if err != nil {
+		return err
 	}
+	tracing.InstrumentDB(server.DB)
 
-	server.DB.Debug().AutoMigrate(&models.User{}, &models.Post{}) // database migration
+	server.InitializeRouter()
+	return nil
+}
 
+// InitializeRouter creates the router serving every route of the API
+func (server *Server) InitializeRouter() {
 	server.Router = mux.NewRouter()
-
 	server.initializeRoutes()
 }
 
-func (server *Server) Run(addr string) {
-	fmt.Printf("Listening to port %s", addr)
-	log.Fatal(http.ListenAndServe(addr, server.Router))
+// db is the database for the queries of a request, traced as part of its span
+func (server *Server) db(r *http.Request) *gorm.DB {
+	return tracing.WithContext(server.DB, r.Context())
+}
+
+// Connect opens the database without touching its schema, see the migrations package.
+// Statements are logged at the debug level or when slower than the threshold,
+// errors always are.
+func (server *Server) Connect(cfg config.DBConfig, logs config.LogConfig) error {
+	db, err := database.Open(cfg)
+	if err != nil {
+		return err
+	}
+	db.SetLogger(logging.GormLogger{Logger: slog.Default(), SlowThreshold: logs.SlowQuery})
+	// gorm drops its errors without the log mode, the logger filters the statements
+	db.LogMode(true)
+	slog.Info("connected to the database", "driver", cfg.Driver)
+	server.DB = db
+	return nil
+}
+
+// Run serves the API and starts the background workers. On SIGINT or SIGTERM it
+// fails the readiness check for the drain delay, then stops accepting connections,
+// lets the requests in flight finish within the shutdown timeout, stops the
+// workers and closes the database pool.
+func (server *Server) Run(addr string, cfg config.HTTPConfig) error {
+	srv := &http.Server{
+		Addr:           addr,
+		Handler:        server.Router,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		IdleTimeout:    cfg.IdleTimeout,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if server.Workers != nil {
+		server.Workers.Start()
+	}
+	failed := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", addr)
+		err := srv.ListenAndServe()
+		if err != http.ErrServerClosed {
+			failed <- err
+		}
+	}()
+
+	var err error
+	select {
+	case err = <-failed:
+	case <-signals.Done():
+		// A second signal kills the process without waiting
+		stop()
+		server.Drain()
+		slog.Info("draining", "delay", cfg.DrainDelay.String())
+		// Requests keep being served until load balancers see the failing check
+		time.Sleep(cfg.DrainDelay)
+		slog.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	errs := []error{err, srv.Shutdown(ctx)}
+	if server.Workers != nil {
+		errs = append(errs, server.Workers.Stop(ctx))
+	}
+	errs = append(errs, server.DB.Close())
+	return errors.Join(errs...)
 }
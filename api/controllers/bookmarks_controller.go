@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+func (server *Server) CreateBookmark(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	bookmark := models.Bookmark{}
+	// The body is optional, it only carries the reading list to file the bookmark under
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &bookmark)
+		if err != nil {
+			responses.ERROR(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+	bookmark.Prepare()
+	bookmark.UserID = uid
+	bookmark.PostID = pid
+	err = bookmark.Validate()
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+
+	post := models.Post{}
+	postReceived, err := post.FindPostByID(server.db(r), pid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	if !postReceived.IsPublished() && postReceived.AuthorID != uid {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+	if bookmark.ReadingListID != 0 {
+		list := models.ReadingList{}
+		_, err = list.FindUserReadingList(server.db(r), bookmark.ReadingListID, uid)
+		if err != nil {
+			responses.Problem(w, err)
+			return
+		}
+	}
+
+	// Bookmarking a post twice moves the existing bookmark to the given reading list
+	existing := models.Bookmark{}
+	_, err = existing.FindBookmark(server.db(r), uid, pid)
+	if err == nil {
+		bookmarkMoved, err := existing.MoveBookmark(server.db(r), bookmark.ReadingListID)
+		if err != nil {
+			responses.Problem(w, err)
+			return
+		}
+		responses.JSON(w, http.StatusOK, bookmarkMoved)
+		return
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		responses.Problem(w, err)
+		return
+	}
+	bookmarkCreated, err := bookmark.SaveBookmark(server.db(r))
+	if err != nil {
+		responses.Problem(w, database.TranslateError(err))
+		return
+	}
+	responses.JSON(w, http.StatusCreated, bookmarkCreated)
+}
+
+func (server *Server) DeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	bookmark := models.Bookmark{}
+	_, err = bookmark.DeleteBookmark(server.db(r), uid, pid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.NoContent(w)
+}
+
+func (server *Server) GetMyBookmarks(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	var listID *uint64
+	if v := r.URL.Query().Get("list"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			responses.ERROR(w, http.StatusBadRequest, errors.New("Invalid List"))
+			return
+		}
+		listID = &id
+	}
+
+	bookmark := models.Bookmark{}
+	bookmarks, err := bookmark.FindUserBookmarks(server.db(r), uid, listID, page.Limit, page.Offset)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, bookmarks)
+}
+
+func (server *Server) CreateReadingList(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	list := models.ReadingList{}
+	err = json.Unmarshal(body, &list)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	list.Prepare()
+	list.UserID = uid
+	err = list.Validate()
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	listCreated, err := list.SaveReadingList(server.db(r))
+	if err != nil {
+		responses.Problem(w, database.TranslateError(err))
+		return
+	}
+	responses.JSON(w, http.StatusCreated, listCreated)
+}
+
+func (server *Server) GetMyReadingLists(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	list := models.ReadingList{}
+	lists, err := list.FindReadingLists(server.db(r), uid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, lists)
+}
+
+func (server *Server) DeleteReadingList(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	list := models.ReadingList{}
+	_, err = list.DeleteAReadingList(server.db(r), id, uid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.NoContent(w)
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+type feedPage struct {
+	Posts      []models.Post `json:"posts"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (server *Server) GetFeed(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	limit, err := pagination.LimitFromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	cursor, err := pagination.DecodeCursor(r.URL.Query().Get("cursor"))
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post := models.Post{}
+	// Ask for one extra post to know whether there is a next page
+	posts, err := post.FindFeed(server.db(r), uid, cursor.Time, cursor.ID, limit+1)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+
+	page := feedPage{Posts: *posts}
+	if len(page.Posts) > limit {
+		page.Posts = page.Posts[:limit]
+		last := page.Posts[limit-1]
+		page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	responses.JSON(w, http.StatusOK, page)
+}
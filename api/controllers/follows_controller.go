@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+func (server *Server) FollowUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	tokenID, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	follow := models.Follow{FollowerID: tokenID, FollowingID: uint32(uid)}
+	follow.Prepare()
+	err = follow.Validate()
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	following, err := follow.IsFollowing(server.db(r), follow.FollowerID, follow.FollowingID)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	if following {
+		responses.ERROR(w, http.StatusConflict, errors.New("Already Following"))
+		return
+	}
+	followCreated, err := follow.SaveFollow(server.db(r))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			responses.ERROR(w, http.StatusNotFound, errors.New("User not found"))
+			return
+		}
+		responses.Problem(w, database.TranslateError(err))
+		return
+	}
+	responses.JSON(w, http.StatusCreated, followCreated)
+}
+
+func (server *Server) UnfollowUser(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	tokenID, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	follow := models.Follow{}
+	_, err = follow.DeleteFollow(server.db(r), tokenID, uint32(uid))
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.NoContent(w)
+}
+
+func (server *Server) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	follow := models.Follow{}
+	users, err := follow.FindFollowers(server.db(r), uint32(uid), page.Limit, page.Offset)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, users)
+}
+
+func (server *Server) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	follow := models.Follow{}
+	users, err := follow.FindFollowing(server.db(r), uint32(uid), page.Limit, page.Offset)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, users)
+}
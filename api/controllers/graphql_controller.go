@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/planutim/postgres-copy/api/apperrors"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/logging"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+const (
+	// GraphQLMaxComplexity bounds the rows a query may ask for: every field costs
+	// one, times the limit of the lists it is in
+	GraphQLMaxComplexity = 10000
+	// GraphQLMaxDepth bounds the nesting of the fields of a query
+	GraphQLMaxDepth = 8
+)
+
+// graphqlQuery is a GraphQL request, the body of a POST or the query string of a GET
+type graphqlQuery struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQL answers queries and mutations over users and posts. The token is read like
+// for every other route; without one, only public data can be queried.
+func (server *Server) GraphQL(w http.ResponseWriter, r *http.Request) {
+	query, err := readGraphQLQuery(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	uid := uint32(0)
+	if auth.ExtractToken(r) != "" {
+		uid, err = auth.ExtractTokenID(r)
+		if err != nil {
+			responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+			return
+		}
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query.Query), Name: "GraphQL request"})})
+	if err != nil {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	validation := graphql.ValidateDocument(&graphqlSchema, doc, nil)
+	if !validation.IsValid {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Result{Errors: validation.Errors})
+		return
+	}
+	operation := findOperation(doc, query.OperationName)
+	if operation == nil {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(errors.New("Unknown operation"))})
+		return
+	}
+	// A GET must not change anything, caches and crawlers send them freely
+	if r.Method == http.MethodGet && operation.Operation != ast.OperationTypeQuery {
+		responses.ERROR(w, http.StatusMethodNotAllowed, errors.New("Mutations need a POST request"))
+		return
+	}
+	err = checkComplexity(doc, operation, query.Variables)
+	if err != nil {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if operation.Name != nil {
+		logging.AddFields(r.Context(), "graphql_operation", operation.Name.Value)
+	}
+
+	ctx := context.WithValue(r.Context(), graphqlRequestKey{}, newGraphQLRequest(server, server.db(r), uid))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphqlSchema,
+		AST:           doc,
+		OperationName: query.OperationName,
+		Args:          query.Variables,
+		Context:       ctx,
+	})
+	for i := range result.Errors {
+		result.Errors[i] = graphqlError(r, result.Errors[i])
+	}
+	writeGraphQL(w, http.StatusOK, result)
+}
+
+func readGraphQLQuery(r *http.Request) (graphqlQuery, error) {
+	query := graphqlQuery{}
+	if r.Method == http.MethodGet {
+		values := r.URL.Query()
+		query.Query = values.Get("query")
+		query.OperationName = values.Get("operationName")
+		if v := values.Get("variables"); v != "" {
+			err := json.Unmarshal([]byte(v), &query.Variables)
+			if err != nil {
+				return query, apperrors.NewValidation("Invalid Variables")
+			}
+		}
+	} else {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return query, err
+		}
+		err = json.Unmarshal(body, &query)
+		if err != nil {
+			return query, err
+		}
+	}
+	if strings.TrimSpace(query.Query) == "" {
+		return query, apperrors.NewValidation("Required Query")
+	}
+	return query, nil
+}
+
+func writeGraphQL(w http.ResponseWriter, statusCode int, result *graphql.Result) {
+	responses.JSON(w, statusCode, result)
+}
+
+// graphqlError gives the error of a resolver the kind of problem it is, in its
+// extensions. The message of an internal error is logged, never shown.
+func graphqlError(r *http.Request, e gqlerrors.FormattedError) gqlerrors.FormattedError {
+	cause := originalGraphQLError(e)
+	if cause == nil {
+		return e
+	}
+	kind := apperrors.KindOf(cause)
+	if kind == apperrors.Internal {
+		logging.FromContext(r.Context()).Error("graphql resolver failed", "path", fmt.Sprint(e.Path), "error", cause)
+		e.Message = http.StatusText(http.StatusInternalServerError)
+	}
+	e.Extensions = map[string]interface{}{"type": responses.ProblemType(kind), "status": kind.Status()}
+	if fields := apperrors.Fields(cause); len(fields) > 0 {
+		e.Extensions["errors"] = fields
+	}
+	return e
+}
+
+// originalGraphQLError is the error a resolver returned, nil for the errors of the
+// query itself
+func originalGraphQLError(e gqlerrors.FormattedError) error {
+	err := e.OriginalError()
+	for {
+		switch v := err.(type) {
+		case *gqlerrors.Error:
+			if v.OriginalError == nil {
+				return nil
+			}
+			err = v.OriginalError
+		case gqlerrors.FormattedError:
+			err = v.OriginalError()
+		default:
+			return err
+		}
+	}
+}
+
+// findOperation is the operation to run, the only one unless name picks one
+func findOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" && found != nil {
+			return nil
+		}
+		if name == "" || (operation.Name != nil && operation.Name.Value == name) {
+			found = operation
+		}
+	}
+	return found
+}
+
+// complexity measures a query against the schema before it runs
+type complexity struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkComplexity rejects the operations nested too deep or asking for too many
+// rows. Introspection is exempt, tools ask for the whole schema.
+func checkComplexity(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) error {
+	c := complexity{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	for _, definition := range doc.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	root := graphqlSchema.QueryType()
+	if operation.Operation == ast.OperationTypeMutation {
+		root = graphqlSchema.MutationType()
+	}
+	cost, depth := c.selections(operation.SelectionSet, root, 1)
+	if depth > GraphQLMaxDepth {
+		return fmt.Errorf("Query Too Deep: %d levels, at most %d", depth, GraphQLMaxDepth)
+	}
+	if cost > GraphQLMaxComplexity {
+		return fmt.Errorf("Query Too Complex: cost %d, at most %d", cost, GraphQLMaxComplexity)
+	}
+	return nil
+}
+
+func (c complexity) selections(set *ast.SelectionSet, parent *graphql.Object, depth int) (int, int) {
+	if set == nil || parent == nil {
+		return 0, depth - 1
+	}
+	cost, maxDepth := 0, depth
+	add := func(fieldCost, fieldDepth int) {
+		cost += fieldCost
+		if fieldDepth > maxDepth {
+			maxDepth = fieldDepth
+		}
+	}
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			add(c.field(s, parent, depth))
+		case *ast.InlineFragment:
+			add(c.selections(s.SelectionSet, parent, depth))
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[s.Name.Value]; ok {
+				add(c.selections(fragment.SelectionSet, parent, depth))
+			}
+		}
+	}
+	return cost, maxDepth
+}
+
+func (c complexity) field(field *ast.Field, parent *graphql.Object, depth int) (int, int) {
+	definition, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return 1, depth
+	}
+	child, _ := graphql.GetNamed(definition.Type).(*graphql.Object)
+	cost, maxDepth := c.selections(field.SelectionSet, child, depth+1)
+	if _, ok := graphql.GetNullable(definition.Type).(*graphql.List); ok {
+		cost *= c.listSize(field, definition)
+	}
+	return 1 + cost, maxDepth
+}
+
+// listSize is the limit of a list field, the largest one for lists without a limit
+func (c complexity) listSize(field *ast.Field, definition *graphql.FieldDefinition) int {
+	size := pagination.MaxLimit
+	for _, arg := range definition.Args {
+		if arg.Name() == "limit" {
+			if limit, ok := arg.DefaultValue.(int); ok {
+				size = limit
+			}
+		}
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			fmt.Sscan(v.Value, &size)
+		case *ast.Variable:
+			if limit, ok := c.variables[v.Name.Value].(float64); ok {
+				size = int(limit)
+			}
+		}
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
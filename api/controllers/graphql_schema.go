@@ -0,0 +1,474 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/utils/dataloader"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+// graphqlRequest is what the resolvers of a request share: its database, the user
+// of its token, 0 when it has none, and the loaders batching its lookups
+type graphqlRequest struct {
+	server        *Server
+	db            *gorm.DB
+	uid           uint32
+	users         *dataloader.Loader[uint32, models.User]
+	postsByAuthor *dataloader.Loader[authorPosts, []models.Post]
+}
+
+// authorPosts is the key of the posts of an author: the limit is applied by the
+// query, each limit asked for in a batch costs one
+type authorPosts struct {
+	authorID uint32
+	limit    int
+}
+
+type graphqlRequestKey struct{}
+
+func newGraphQLRequest(server *Server, db *gorm.DB, uid uint32) *graphqlRequest {
+	return &graphqlRequest{
+		server: server,
+		db:     db,
+		uid:    uid,
+		users: dataloader.New(func(ids []uint32) (map[uint32]models.User, error) {
+			user := models.User{}
+			users, err := user.FindUsersByIDs(db, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uint32]models.User, len(*users))
+			for _, u := range *users {
+				byID[u.ID] = u
+			}
+			return byID, nil
+		}),
+		postsByAuthor: dataloader.New(func(keys []authorPosts) (map[authorPosts][]models.Post, error) {
+			idsByLimit := map[int][]uint32{}
+			for _, k := range keys {
+				idsByLimit[k.limit] = append(idsByLimit[k.limit], k.authorID)
+			}
+			byAuthor := make(map[authorPosts][]models.Post, len(keys))
+			for limit, ids := range idsByLimit {
+				post := models.Post{}
+				posts, err := post.FindPostsByAuthors(db, ids, limit)
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range *posts {
+					key := authorPosts{authorID: p.AuthorID, limit: limit}
+					byAuthor[key] = append(byAuthor[key], p)
+				}
+			}
+			return byAuthor, nil
+		}),
+	}
+}
+
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	return ctx.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+// signedIn is the user of the token of the request, mutations need one
+func (req *graphqlRequest) signedIn() (uint32, error) {
+	if req.uid == 0 {
+		return 0, apperrors.NewUnauthorized("Unauthorized")
+	}
+	return req.uid, nil
+}
+
+// graphqlSchema is built once, the state of a request goes through its context
+var graphqlSchema = mustGraphQLSchema()
+
+func mustGraphQLSchema() graphql.Schema {
+	var postType *graphql.Object
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        idField(func(u *models.User) uint64 { return uint64(u.ID) }),
+				"nickname":  userField(graphql.String, func(u *models.User) interface{} { return u.Nickname }),
+				"email":     userField(graphql.String, func(u *models.User) interface{} { return u.Email }),
+				"createdAt": userField(graphql.DateTime, func(u *models.User) interface{} { return u.CreatedAt }),
+				"updatedAt": userField(graphql.DateTime, func(u *models.User) interface{} { return u.UpdatedAt }),
+				"posts": &graphql.Field{
+					Description: "The published posts of the user, newest first",
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+					Args:        limitArgs(),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						limit, err := limitArg(p)
+						if err != nil {
+							return nil, err
+						}
+						load := graphqlRequestFrom(p.Context).postsByAuthor.Load(authorPosts{authorID: p.Source.(*models.User).ID, limit: limit})
+						return func() (interface{}, error) {
+							posts, _, err := load()
+							if err != nil {
+								return nil, err
+							}
+							return postPointers(posts), nil
+						}, nil
+					},
+				},
+			}
+		}),
+	})
+
+	reactionsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Reactions",
+		Description: "How many times each reaction was given to a post",
+		Fields:      graphql.Fields{},
+	})
+	for _, kind := range models.ReactionTypes {
+		kind := kind
+		reactionsType.AddFieldConfig(kind, &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Int),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(map[string]int64)[kind], nil
+			},
+		})
+	}
+
+	postType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.Fields{
+			"id":          idField(func(p *models.Post) uint64 { return p.ID }),
+			"title":       postField(graphql.String, func(p *models.Post) interface{} { return p.Title }),
+			"slug":        postField(graphql.String, func(p *models.Post) interface{} { return p.Slug }),
+			"content":     postField(graphql.String, func(p *models.Post) interface{} { return p.Content }),
+			"contentHtml": postField(graphql.String, func(p *models.Post) interface{} { return p.ContentHTML }),
+			"excerpt":     postField(graphql.String, func(p *models.Post) interface{} { return p.Excerpt }),
+			"status":      postField(graphql.String, func(p *models.Post) interface{} { return p.Status }),
+			"tags":        postField(graphql.NewList(graphql.NewNonNull(graphql.String)), func(p *models.Post) interface{} { return p.Tags }),
+			"mentions":    postField(graphql.NewList(graphql.NewNonNull(graphql.String)), func(p *models.Post) interface{} { return p.Mentions }),
+			"reactions": postField(reactionsType, func(p *models.Post) interface{} {
+				if p.Reactions == nil {
+					return map[string]int64{}
+				}
+				return p.Reactions
+			}),
+			"reactionCount": postField(graphql.Int, func(p *models.Post) interface{} {
+				total := int64(0)
+				for _, count := range p.Reactions {
+					total += count
+				}
+				return total
+			}),
+			"wordCount":          postField(graphql.Int, func(p *models.Post) interface{} { return p.WordCount }),
+			"readingTimeMinutes": postField(graphql.Int, func(p *models.Post) interface{} { return p.ReadingTimeMinutes }),
+			"createdAt":          postField(graphql.DateTime, func(p *models.Post) interface{} { return p.CreatedAt }),
+			"updatedAt":          postField(graphql.DateTime, func(p *models.Post) interface{} { return p.UpdatedAt }),
+			"author": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					post := p.Source.(*models.Post)
+					// Lists of posts usually come with their authors, the others are batched
+					if post.Author.ID != 0 {
+						return &post.Author, nil
+					}
+					load := graphqlRequestFrom(p.Context).users.Load(post.AuthorID)
+					return func() (interface{}, error) {
+						user, ok, err := load()
+						if err != nil || !ok {
+							return nil, err
+						}
+						return &user, nil
+					}, nil
+				},
+			},
+		},
+	})
+
+	postInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "PostInput",
+		Description: "A post of the signed in user, an empty status publishes it",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"content": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"excerpt": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"status":  &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Description: "The user of the token, null without one",
+				Type:        userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					req := graphqlRequestFrom(p.Context)
+					if req.uid == 0 {
+						return nil, nil
+					}
+					return findUser(req, req.uid)
+				},
+			},
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					uid, err := strconv.ParseUint(p.Args["id"].(string), 10, 32)
+					if err != nil {
+						return nil, apperrors.NewValidation("Invalid ID")
+					}
+					return findUser(graphqlRequestFrom(p.Context), uint32(uid))
+				},
+			},
+			"users": &graphql.Field{
+				Description: "At most 100 users",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := models.User{}
+					users, err := user.FindAllUsers(graphqlRequestFrom(p.Context).db)
+					if err != nil {
+						return nil, err
+					}
+					result := make([]*models.User, len(*users))
+					for i := range *users {
+						result[i] = &(*users)[i]
+					}
+					return result, nil
+				},
+			},
+			"post": &graphql.Field{
+				Description: "The post with the ID or the slug, drafts are only found by their author",
+				Type:        postType,
+				Args: graphql.FieldConfigArgument{
+					"id":   &graphql.ArgumentConfig{Type: graphql.ID},
+					"slug": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolvePost,
+			},
+			"posts": &graphql.Field{
+				Description: "The latest published posts, with the tag when given",
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+				Args: graphql.FieldConfigArgument{
+					"tag":   &graphql.ArgumentConfig{Type: graphql.String},
+					"limit": limitArgs()["limit"],
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, err := limitArg(p)
+					if err != nil {
+						return nil, err
+					}
+					tag, _ := p.Args["tag"].(string)
+					post := models.Post{}
+					posts, err := post.FindRecentPosts(graphqlRequestFrom(p.Context).db, 0, tag, limit)
+					if err != nil {
+						return nil, err
+					}
+					return postPointers(*posts), nil
+				},
+			},
+		},
+	})
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"login": &graphql.Field{
+				Description: "A token to send as a bearer token",
+				Type:        graphql.NewNonNull(graphql.String),
+				Args: graphql.FieldConfigArgument{
+					"email":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"password": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := models.User{Email: p.Args["email"].(string), Password: p.Args["password"].(string)}
+					user.Prepare()
+					err := user.Validate("login")
+					if err != nil {
+						return nil, err
+					}
+					return graphqlRequestFrom(p.Context).server.SignIn(user.Email, user.Password)
+				},
+			},
+			"createPost": &graphql.Field{
+				Type: graphql.NewNonNull(postType),
+				Args: graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(postInputType)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					req := graphqlRequestFrom(p.Context)
+					uid, err := req.signedIn()
+					if err != nil {
+						return nil, err
+					}
+					post := postFromInput(p.Args["input"], uid)
+					return createPost(req.db, uid, &post)
+				},
+			},
+			"updatePost": &graphql.Field{
+				Type: graphql.NewNonNull(postType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(postInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					req := graphqlRequestFrom(p.Context)
+					uid, err := req.signedIn()
+					if err != nil {
+						return nil, err
+					}
+					pid, err := strconv.ParseUint(p.Args["id"].(string), 10, 64)
+					if err != nil {
+						return nil, apperrors.NewValidation("Invalid ID")
+					}
+					post, err := findOwnPost(req.db, uid, pid)
+					if err != nil {
+						return nil, err
+					}
+					postUpdate := postFromInput(p.Args["input"], uid)
+					return updatePost(req.db, uid, post, &postUpdate)
+				},
+			},
+			"deletePost": &graphql.Field{
+				Description: "The ID of the deleted post",
+				Type:        graphql.NewNonNull(graphql.ID),
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					req := graphqlRequestFrom(p.Context)
+					uid, err := req.signedIn()
+					if err != nil {
+						return nil, err
+					}
+					pid, err := strconv.ParseUint(p.Args["id"].(string), 10, 64)
+					if err != nil {
+						return nil, apperrors.NewValidation("Invalid ID")
+					}
+					post, err := findOwnPost(req.db, uid, pid)
+					if err != nil {
+						return nil, err
+					}
+					_, err = post.DeleteAPost(req.db, pid, uid)
+					if err != nil {
+						return nil, err
+					}
+					return p.Args["id"], nil
+				},
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType, Mutation: mutationType})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// idField resolves the ID of a user or a post, IDs are strings in GraphQL
+func idField[T any](id func(*T) uint64) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.ID),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return strconv.FormatUint(id(p.Source.(*T)), 10), nil
+		},
+	}
+}
+
+func userField(typ graphql.Output, value func(*models.User) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(typ),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return value(p.Source.(*models.User)), nil
+		},
+	}
+}
+
+func postField(typ graphql.Output, value func(*models.Post) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(typ),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return value(p.Source.(*models.Post)), nil
+		},
+	}
+}
+
+func limitArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"limit": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: pagination.DefaultLimit,
+			Description:  "At most 100",
+		},
+	}
+}
+
+func limitArg(p graphql.ResolveParams) (int, error) {
+	limit, _ := p.Args["limit"].(int)
+	if limit < 1 || limit > pagination.MaxLimit {
+		return 0, apperrors.NewValidation("Invalid Limit")
+	}
+	return limit, nil
+}
+
+func postPointers(posts []models.Post) []*models.Post {
+	result := make([]*models.Post, len(posts))
+	for i := range posts {
+		result[i] = &posts[i]
+	}
+	return result
+}
+
+func postFromInput(input interface{}, uid uint32) models.Post {
+	fields := input.(map[string]interface{})
+	post := models.Post{AuthorID: uid}
+	post.Title, _ = fields["title"].(string)
+	post.Content, _ = fields["content"].(string)
+	post.Excerpt, _ = fields["excerpt"].(string)
+	post.Status, _ = fields["status"].(string)
+	return post
+}
+
+// findUser is the user with the ID, or null when there is none
+func findUser(req *graphqlRequest, uid uint32) (interface{}, error) {
+	load := req.users.Load(uid)
+	return func() (interface{}, error) {
+		user, ok, err := load()
+		if err != nil || !ok {
+			return nil, err
+		}
+		return &user, nil
+	}, nil
+}
+
+func resolvePost(p graphql.ResolveParams) (interface{}, error) {
+	req := graphqlRequestFrom(p.Context)
+	post := models.Post{}
+	var found *models.Post
+	var err error
+	if id, ok := p.Args["id"].(string); ok {
+		pid, parseErr := strconv.ParseUint(id, 10, 64)
+		if parseErr != nil {
+			return nil, apperrors.NewValidation("Invalid ID")
+		}
+		found, err = post.FindPostByID(req.db, pid)
+	} else if slug, ok := p.Args["slug"].(string); ok {
+		found, err = post.FindPostBySlug(req.db, slug)
+		if gorm.IsRecordNotFoundError(err) {
+			// An old slug of a renamed post finds the post under its current slug
+			current, currentErr := post.FindCurrentSlug(req.db, slug)
+			if currentErr == nil {
+				found, err = post.FindPostBySlug(req.db, current)
+			}
+		}
+	} else {
+		return nil, apperrors.NewValidation("Either id or slug is required")
+	}
+	if gorm.IsRecordNotFoundError(err) || apperrors.KindOf(err) == apperrors.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !found.IsPublished() && found.AuthorID != req.uid {
+		return nil, nil
+	}
+	return found, nil
+}
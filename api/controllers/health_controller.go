@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/workers"
+)
+
+// ReadinessTimeout bounds how long the readiness check waits for the database
+var ReadinessTimeout = 2 * time.Second
+
+type check struct {
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"duration_ms,omitempty"`
+	// Pending migrations, for the migrations check
+	Pending *int `json:"pending,omitempty"`
+	// Workers, for the workers check
+	Workers []workers.Health `json:"workers,omitempty"`
+}
+
+type readiness struct {
+	Status string           `json:"status"`
+	Checks map[string]check `json:"checks"`
+}
+
+// Healthz is the liveness probe, it only tells that the process serves requests
+func (server *Server) Healthz(w http.ResponseWriter, r *http.Request) {
+	responses.JSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz is the readiness probe: the database answers in time, no migration is
+// pending, every background worker runs and the server is not shutting down. A
+// worker whose last run failed only degrades the workers check, the next run may
+// succeed and the API serves requests meanwhile.
+func (server *Server) Readyz(w http.ResponseWriter, r *http.Request) {
+	result := readiness{Status: "ok", Checks: map[string]check{}}
+	fail := func(name string, c check) {
+		c.Status = "failing"
+		result.Checks[name] = c
+		result.Status = "unavailable"
+	}
+
+	if server.draining.Load() {
+		fail("shutdown", check{Error: "Server is shutting down"})
+	} else {
+		result.Checks["shutdown"] = check{Status: "ok"}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ReadinessTimeout)
+	defer cancel()
+	start := time.Now()
+	err := server.DB.DB().PingContext(ctx)
+	database := check{Status: "ok", Duration: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		database.Error = err.Error()
+		fail("database", database)
+		// Without a database the migration status cannot be read either
+		fail("migrations", check{Error: "Database unavailable"})
+	} else {
+		result.Checks["database"] = database
+		result.Checks["migrations"] = server.migrationCheck()
+		if result.Checks["migrations"].Status != "ok" {
+			result.Status = "unavailable"
+		}
+	}
+
+	if server.Workers != nil {
+		health := server.Workers.Health()
+		c := check{Status: "ok", Workers: health}
+		for _, h := range health {
+			if !h.Running {
+				c.Status = "failing"
+				c.Error = "A worker stopped"
+				result.Status = "unavailable"
+			} else if h.LastError != "" && c.Status == "ok" {
+				c.Status = "degraded"
+				c.Error = "The last run of a worker failed"
+			}
+		}
+		result.Checks["workers"] = c
+	}
+
+	status := http.StatusOK
+	if result.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	responses.JSON(w, status, result)
+}
+
+// migrationCheck reads the status of the migrations, never writing to the database:
+// a probe creating the schema_migrations table would change the schema it checks
+func (server *Server) migrationCheck() check {
+	migrator := server.Migrator
+	if migrator == nil {
+		var err error
+		migrator, err = migrations.Open(server.DB)
+		if err != nil {
+			return check{Status: "failing", Error: err.Error()}
+		}
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return check{Status: "failing", Error: err.Error()}
+	}
+	c := check{Status: "ok", Pending: &pending}
+	if pending > 0 {
+		c.Status = "failing"
+		c.Error = "Migrations are pending"
+	}
+	return c
+}
+
+// Drain makes the readiness check fail, so that load balancers stop sending new
+// requests before the server shuts down
+func (server *Server) Drain() {
+	server.draining.Store(true)
+}
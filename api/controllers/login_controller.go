@@ -2,19 +2,23 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
 	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/metrics"
 	"github.com/planutim/postgres-copy/api/models"
 	"github.com/planutim/postgres-copy/api/responses"
-	"github.com/planutim/postgres-copy/api/utils/formaterror"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
+		metrics.LoginFailures.Inc()
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
@@ -22,6 +26,7 @@ func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	user := models.User{}
 	err = json.Unmarshal(body, &user)
 	if err != nil {
+		metrics.LoginFailures.Inc()
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
 		return
 	}
@@ -29,28 +34,37 @@ func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 	user.Prepare()
 	err = user.Validate("login")
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		metrics.LoginFailures.Inc()
+		responses.Problem(w, err)
 		return
 	}
 	token, err := server.SignIn(user.Email, user.Password)
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusUnprocessableEntity, formattedError)
+		metrics.LoginFailures.Inc()
+		responses.Problem(w, err)
 		return
 	}
 	responses.JSON(w, http.StatusOK, token)
 }
 
+// SignIn creates a token for the user with these credentials. An unknown email or a
+// wrong password is an unauthorized error.
 func (server *Server) SignIn(email, password string) (string, error) {
 	var err error
 	user := models.User{}
 
-	err = server.DB.Debug().Model(models.User{}).Where("email = ?", email).Take(&user).Error
+	err = server.DB.Model(models.User{}).Where("email = ?", email).Take(&user).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return "", apperrors.Wrap(apperrors.Unauthorized, "Incorrect Details", err)
+	}
 	if err != nil {
 		return "", err
 	}
 	err = models.VerifyPassword(user.Password, password)
-	if err != nil && err == bcrypt.ErrMismatchedHashAndPassword {
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return "", apperrors.Wrap(apperrors.Unauthorized, "Incorrect Password", err)
+	}
+	if err != nil {
 		return "", err
 	}
 	return auth.CreateToken(user.ID)
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+func (server *Server) GetMyNotifications(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notification := models.Notification{}
+	notifications, err := notification.FindUserNotifications(server.db(r), uid, unreadOnly, page.Limit, page.Offset)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, notifications)
+}
+
+func (server *Server) ReadNotification(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	notification := models.Notification{}
+	notificationRead, err := notification.MarkAsRead(server.db(r), id, uid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, notificationRead)
+}
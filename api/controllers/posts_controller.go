@@ -7,15 +7,24 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
 	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/metrics"
 	"github.com/planutim/postgres-copy/api/models"
 	"github.com/planutim/postgres-copy/api/responses"
-	"github.com/planutim/postgres-copy/api/utils/formaterror"
 )
 
 func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
@@ -28,26 +37,9 @@ func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post.Prepare()
-
-	err = post.Validate()
+	postCreated, err := createPost(server.db(r), uid, &post)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
-		return
-	}
-	uid, err := auth.ExtractTokenID(r)
-	if err != nil {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
-		return
-	}
-	if uid != post.AuthorID {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText((http.StatusUnauthorized))))
-		return
-	}
-	postCreated, err := post.SavePost(server.DB)
-	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, err)
 		return
 	}
 	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.URL.Path, postCreated.ID))
@@ -57,9 +49,14 @@ func (server *Server) CreatePost(w http.ResponseWriter, r *http.Request) {
 func (server *Server) GetPosts(w http.ResponseWriter, r *http.Request) {
 	post := models.Post{}
 
-	posts, err := post.FindAllPosts(server.DB)
+	posts, err := post.FindAllPosts(server.db(r))
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.Problem(w, err)
+		return
+	}
+	err = server.attachMyReactions(r, *posts)
+	if err != nil {
+		responses.Problem(w, err)
 		return
 	}
 
@@ -75,12 +72,67 @@ func (server *Server) GetPost(w http.ResponseWriter, r *http.Request) {
 	}
 	post := models.Post{}
 
-	postReceived, err := post.FindPostByID(server.DB, pid)
+	postReceived, err := post.FindPostByID(server.db(r), pid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	server.respondWithPost(w, r, postReceived)
+}
+
+func (server *Server) GetPostBySlug(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	post := models.Post{}
+
+	postReceived, err := post.FindPostBySlug(server.db(r), vars["slug"])
+	if err == nil {
+		server.respondWithPost(w, r, postReceived)
+		return
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		responses.Problem(w, err)
+		return
+	}
+
+	// An old slug of a renamed post redirects to the current one
+	slug, err := post.FindCurrentSlug(server.db(r), vars["slug"])
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
 		return
 	}
-	responses.JSON(w, http.StatusOK, postReceived)
+	location := strings.TrimSuffix(r.URL.Path, vars["slug"]) + slug
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", location)
+	responses.JSON(w, http.StatusMovedPermanently, struct {
+		Slug string `json:"slug"`
+	}{
+		Slug: slug,
+	})
+}
+
+// respondWithPost writes a single post, hiding drafts from everybody but their author
+func (server *Server) respondWithPost(w http.ResponseWriter, r *http.Request, postReceived *models.Post) {
+	if !postReceived.IsPublished() {
+		uid, err := auth.ExtractTokenID(r)
+		if err != nil || uid != postReceived.AuthorID {
+			responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+			return
+		}
+	}
+	posts := []models.Post{*postReceived}
+	err := server.attachMyReactions(r, posts)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	err = posts[0].Format(r.URL.Query().Get("format"))
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, posts[0])
 }
 
 func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
@@ -98,17 +150,9 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
-	//Check if the post exist
-	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	post, err := findOwnPost(server.db(r), uid, pid)
 	if err != nil {
-		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
-		return
-	}
-
-	// If a user attempt to update a post not belonging to him
-	if uid != post.AuthorID {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		responses.Problem(w, err)
 		return
 	}
 
@@ -127,25 +171,9 @@ func (server *Server) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Also check if the request user id is equal to the one gotten from token
-	if uid != postUpdate.AuthorID {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
-		return
-	}
-
-	postUpdate.Prepare()
-	err = postUpdate.Validate()
+	postUpdated, err := updatePost(server.db(r), uid, post, &postUpdate)
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
-		return
-	}
-
-	postUpdate.ID = post.ID // this is important to tell the model the post id to update, the other update field are set above
-	postUpdated, err := postUpdate.UpdateAPost(server.DB)
-
-	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, err)
 		return
 	}
 
@@ -169,25 +197,83 @@ func (server *Server) DeletePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the post exist
-	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	post, err := findOwnPost(server.db(r), uid, pid)
 	if err != nil {
-		responses.ERROR(w, http.StatusNotFound, errors.New("Unauthorized"))
+		responses.Problem(w, err)
 		return
 	}
 
-	// Is the authenticated user, the owner of this post?
-	if uid != post.AuthorID {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+	_, err = post.DeleteAPost(server.db(r), pid, uid)
+	if err != nil {
+		responses.Problem(w, err)
 		return
 	}
+	w.Header().Set("Entity", fmt.Sprintf("%d", pid))
+	responses.NoContent(w)
+}
 
-	_, err = post.DeleteAPost(server.DB, pid, uid)
+// createPost validates and saves a post written by the user uid, for every API
+// creating posts
+func createPost(db *gorm.DB, uid uint32, post *models.Post) (*models.Post, error) {
+	post.Prepare()
+	err := post.Validate()
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
-		return
+		return nil, err
 	}
-	w.Header().Set("Entity", fmt.Sprintf("%d", pid))
-	responses.JSON(w, http.StatusNoContent, "")
+	// Nobody can post on behalf of another user
+	if uid != post.AuthorID {
+		return nil, apperrors.NewForbidden("Forbidden")
+	}
+	postCreated, err := post.SavePost(db)
+	if err != nil {
+		return nil, database.TranslateError(err)
+	}
+	if postCreated.IsPublished() {
+		metrics.PostsPublished.Inc()
+	}
+	return postCreated, nil
+}
+
+// findOwnPost returns the post pid when the user uid wrote it
+func findOwnPost(db *gorm.DB, uid uint32, pid uint64) (*models.Post, error) {
+	post := models.Post{}
+	err := db.Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, apperrors.NewNotFound("Post not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// If a user attempt to update a post not belonging to him
+	if uid != post.AuthorID {
+		return nil, apperrors.NewForbidden("Forbidden")
+	}
+	return &post, nil
+}
+
+// updatePost validates and applies the update of a post found by findOwnPost
+func updatePost(db *gorm.DB, uid uint32, post *models.Post, postUpdate *models.Post) (*models.Post, error) {
+	//Also check if the request user id is equal to the one gotten from token
+	if uid != postUpdate.AuthorID {
+		return nil, apperrors.NewForbidden("Forbidden")
+	}
+	// Only new posts default to published, an update without a status keeps it
+	if strings.TrimSpace(postUpdate.Status) == "" {
+		postUpdate.Status = post.Status
+	}
+	postUpdate.Prepare()
+	err := postUpdate.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	postUpdate.ID = post.ID // this is important to tell the model the post id to update, the other update field are set above
+	postUpdated, err := postUpdate.UpdateAPost(db)
+	if err != nil {
+		return nil, database.TranslateError(err)
+	}
+	if !post.IsPublished() && postUpdated.IsPublished() {
+		metrics.PostsPublished.Inc()
+	}
+	return postUpdated, nil
 }
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+)
+
+type postReactions struct {
+	PostID      uint64           `json:"post_id"`
+	Reactions   map[string]int64 `json:"reactions"`
+	MyReactions []string         `json:"my_reactions"`
+}
+
+func (server *Server) GetReactions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	post := models.Post{}
+	postReceived, err := post.FindPostByID(server.db(r), pid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	// The reactions of a draft are as hidden as the draft itself
+	if !postReceived.IsPublished() {
+		uid, err := auth.ExtractTokenID(r)
+		if err != nil || uid != postReceived.AuthorID {
+			responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+			return
+		}
+	}
+	posts := []models.Post{*postReceived}
+	err = server.attachMyReactions(r, posts)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	mine := posts[0].MyReactions
+	if mine == nil {
+		mine = []string{}
+	}
+	responses.JSON(w, http.StatusOK, postReactions{
+		PostID:      postReceived.ID,
+		Reactions:   postReceived.Reactions,
+		MyReactions: mine,
+	})
+}
+
+func (server *Server) CreateReaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	reaction := models.Reaction{}
+	err = json.Unmarshal(body, &reaction)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	reaction.Prepare()
+	reaction.PostID = pid
+	reaction.UserID = uid
+	err = reaction.Validate()
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+
+	// Only published posts, or the author's own drafts, can be reacted to
+	post := models.Post{}
+	postReceived, err := post.FindPostByID(server.db(r), pid)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	if !postReceived.IsPublished() && postReceived.AuthorID != uid {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Post not found"))
+		return
+	}
+	reacted, err := reaction.HasReacted(server.db(r))
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	if reacted {
+		responses.ERROR(w, http.StatusConflict, errors.New("Already Reacted"))
+		return
+	}
+	reactionCreated, err := reaction.SaveReaction(server.db(r))
+	if err != nil {
+		responses.Problem(w, database.TranslateError(err))
+		return
+	}
+	responses.JSON(w, http.StatusCreated, reactionCreated)
+}
+
+func (server *Server) DeleteReaction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	pid, err := strconv.ParseUint(vars["id"], 10, 64)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	if !models.IsReactionType(vars["type"]) {
+		responses.ERROR(w, http.StatusBadRequest, errors.New("Invalid Reaction Type"))
+		return
+	}
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
+		return
+	}
+
+	reaction := models.Reaction{}
+	_, err = reaction.DeleteReaction(server.db(r), pid, uid, vars["type"])
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.NoContent(w)
+}
+
+// attachMyReactions fills MyReactions on the posts when the request carries a valid token
+func (server *Server) attachMyReactions(r *http.Request, posts []models.Post) error {
+	uid, err := auth.ExtractTokenID(r)
+	if err != nil || uid == 0 || len(posts) == 0 {
+		return nil
+	}
+	pids := make([]uint64, 0, len(posts))
+	for i := range posts {
+		pids = append(pids, posts[i].ID)
+	}
+	reaction := models.Reaction{}
+	mine, err := reaction.FindUserReactions(server.db(r), uid, pids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].MyReactions = mine[posts[i].ID]
+	}
+	return nil
+}
@@ -1,24 +1,63 @@
 package controllers
 
-import "github.com/planutim/postgres-copy/api/middlewares"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/planutim/postgres-copy/api/logging"
+	"github.com/planutim/postgres-copy/api/metrics"
+	"github.com/planutim/postgres-copy/api/middlewares"
+	"github.com/planutim/postgres-copy/api/openapi"
+	"github.com/planutim/postgres-copy/api/requestid"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/tracing"
+)
 
 func (s *Server) initializeRoutes() {
 
+	// Every request gets an ID and a span, then is logged and measured, labelled by
+	// the template of its route
+	s.Router.Use(requestid.Middleware, tracing.Middleware, logging.Middleware, metrics.Middleware)
+	s.Router.NotFoundHandler = requestid.Middleware(tracing.Middleware(logging.Middleware(metrics.Middleware(http.HandlerFunc(routeNotFound)))))
+	s.Router.MethodNotAllowedHandler = requestid.Middleware(tracing.Middleware(logging.Middleware(metrics.Middleware(http.HandlerFunc(methodNotAllowed)))))
+
 	s.Router.HandleFunc("/", middlewares.SetMiddlewareJSON(s.Home)).Methods("GET")
 
-	// Login Route
-	s.Router.HandleFunc("/login", middlewares.SetMiddlewareJSON(s.Login)).Methods("POST")
-
-	//Users routers
-	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
-	s.Router.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.GetUsers)).Methods("GET")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser))).Methods("PUT")
-	s.Router.HandleFunc("/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
-
-	//Posts routes
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.CreatePost)).Methods("POST")
-	s.Router.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.GetPosts)).Methods("GET")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPost)).Methods("GET")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdatePost))).Methods("PUT")
-	s.Router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareAuthentication(s.DeletePost)).Methods("DELETE")
+	// Probe routes
+	s.Router.HandleFunc("/healthz", middlewares.SetMiddlewareJSON(s.Healthz)).Methods("GET")
+	s.Router.HandleFunc("/readyz", middlewares.SetMiddlewareJSON(s.Readyz)).Methods("GET")
+	s.Router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Documentation routes
+	s.Router.HandleFunc("/openapi.json", openapi.Handler).Methods("GET")
+	s.Router.HandleFunc("/docs", openapi.DocsHandler).Methods("GET")
+	s.Router.HandleFunc("/docs/assets/{file}", openapi.AssetsHandler).Methods("GET")
+
+	// Feeds and sitemaps follow their own standards, they are not versioned
+	s.Router.HandleFunc("/feeds/posts.{format:rss|atom|json}", s.GetPostsFeed).Methods("GET")
+	s.Router.HandleFunc("/feeds/users/{id}/posts.{format:rss|atom|json}", s.GetUserPostsFeed).Methods("GET")
+	s.Router.HandleFunc("/feeds/tags/{name}/posts.{format:rss|atom|json}", s.GetTagPostsFeed).Methods("GET")
+	s.Router.HandleFunc("/sitemap.xml", s.GetSitemapIndex).Methods("GET")
+	s.Router.HandleFunc("/sitemaps/{section:[a-z]+}-{page:[0-9]+}.xml", s.GetSitemap).Methods("GET")
+
+	// GraphQL evolves its schema in place instead of by versions
+	s.Router.HandleFunc("/graphql", middlewares.SetMiddlewareJSON(s.GraphQL)).Methods("GET", "POST")
+
+	for _, v := range apiVersions {
+		v.routes(s, s.Router.PathPrefix(v.prefix).Subrouter())
+	}
+	// The routes of the first version are still served at the root, until the sunset
+	deprecated := s.Router.NewRoute().Name(DeprecatedRoutes).Subrouter()
+	deprecated.Use(middlewares.SetMiddlewareDeprecation(apiVersions[0].prefix, DeprecatedSince, SunsetAt))
+	apiVersions[0].routes(s, deprecated)
+}
+
+// routeNotFound and methodNotAllowed answer requests matching no route with problem
+// details, like every other error
+func routeNotFound(w http.ResponseWriter, r *http.Request) {
+	responses.ERROR(w, http.StatusNotFound, errors.New("Route not found"))
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	responses.ERROR(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
 }
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/middlewares"
+)
+
+// routesV1 registers the routes of the first version of the JSON API on r
+func routesV1(s *Server, r *mux.Router) {
+	// Login Route
+	r.HandleFunc("/login", middlewares.SetMiddlewareJSON(s.Login)).Methods("POST")
+
+	//Users routers
+	r.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.CreateUser)).Methods("POST")
+	r.HandleFunc("/users", middlewares.SetMiddlewareJSON(s.GetUsers)).Methods("GET")
+	r.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(s.GetUser)).Methods("GET")
+	r.HandleFunc("/users/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdateUser))).Methods("PUT")
+	r.HandleFunc("/users/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteUser)).Methods("DELETE")
+
+	//Follow routes
+	r.HandleFunc("/users/{id}/follow", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.FollowUser))).Methods("POST")
+	r.HandleFunc("/users/{id}/follow", middlewares.SetMiddlewareAuthentication(s.UnfollowUser)).Methods("DELETE")
+	r.HandleFunc("/users/{id}/followers", middlewares.SetMiddlewareJSON(s.GetFollowers)).Methods("GET")
+	r.HandleFunc("/users/{id}/following", middlewares.SetMiddlewareJSON(s.GetFollowing)).Methods("GET")
+	r.HandleFunc("/feed", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.GetFeed))).Methods("GET")
+
+	//Posts routes
+	r.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.CreatePost)).Methods("POST")
+	r.HandleFunc("/posts", middlewares.SetMiddlewareJSON(s.GetPosts)).Methods("GET")
+	r.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(s.GetPost)).Methods("GET")
+	r.HandleFunc("/posts/by-slug/{slug}", middlewares.SetMiddlewareJSON(s.GetPostBySlug)).Methods("GET")
+	r.HandleFunc("/posts/{id}", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.UpdatePost))).Methods("PUT")
+	r.HandleFunc("/posts/{id}", middlewares.SetMiddlewareAuthentication(s.DeletePost)).Methods("DELETE")
+
+	//Reaction routes
+	r.HandleFunc("/posts/{id}/reactions", middlewares.SetMiddlewareJSON(s.GetReactions)).Methods("GET")
+	r.HandleFunc("/posts/{id}/reactions", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CreateReaction))).Methods("POST")
+	r.HandleFunc("/posts/{id}/reactions/{type}", middlewares.SetMiddlewareAuthentication(s.DeleteReaction)).Methods("DELETE")
+
+	//Bookmark routes
+	r.HandleFunc("/posts/{id}/bookmark", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CreateBookmark))).Methods("POST")
+	r.HandleFunc("/posts/{id}/bookmark", middlewares.SetMiddlewareAuthentication(s.DeleteBookmark)).Methods("DELETE")
+	r.HandleFunc("/me/bookmarks", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.GetMyBookmarks))).Methods("GET")
+	r.HandleFunc("/me/reading-lists", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.GetMyReadingLists))).Methods("GET")
+	r.HandleFunc("/me/reading-lists", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.CreateReadingList))).Methods("POST")
+	r.HandleFunc("/me/reading-lists/{id}", middlewares.SetMiddlewareAuthentication(s.DeleteReadingList)).Methods("DELETE")
+
+	//Tag routes
+	r.HandleFunc("/tags/{name}/posts", middlewares.SetMiddlewareJSON(s.GetTagPosts)).Methods("GET")
+
+	//Notification routes
+	r.HandleFunc("/me/notifications", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.GetMyNotifications))).Methods("GET")
+	r.HandleFunc("/me/notifications/{id}/read", middlewares.SetMiddlewareJSON(middlewares.SetMiddlewareAuthentication(s.ReadNotification))).Methods("PUT")
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/logging"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/sitemap"
+)
+
+// sitemapSection is a kind of page listed in the sitemap, split over numbered files
+type sitemapSection struct {
+	name    string
+	count   func(db *gorm.DB) (int, error)
+	each    func(db *gorm.DB, limit, offset int, fn func(models.SitemapEntry) error) error
+	lastMod func(db *gorm.DB, limit, offset int) (time.Time, error)
+}
+
+var sitemapSections = map[string]sitemapSection{
+	"posts": {name: "posts", count: models.CountSitemapPosts, each: models.EachSitemapPost, lastMod: models.SitemapPostsLastMod},
+	"users": {name: "users", count: models.CountSitemapUsers, each: models.EachSitemapUser, lastMod: models.SitemapUsersLastMod},
+}
+
+var sitemapSectionOrder = []string{"posts", "users"}
+
+// GetSitemapIndex lists the sitemap files of every section
+func (server *Server) GetSitemapIndex(w http.ResponseWriter, r *http.Request) {
+	type file struct {
+		loc     string
+		lastMod time.Time
+	}
+	// Everything is looked up before writing so that a failure can still be reported
+	files := []file{}
+	base := baseURL(r)
+	for _, name := range sitemapSectionOrder {
+		section := sitemapSections[name]
+		count, err := section.count(server.db(r))
+		if err != nil {
+			responses.Problem(w, err)
+			return
+		}
+		for page := 1; page <= sitemap.Files(count); page++ {
+			lastMod, err := section.lastMod(server.db(r), sitemap.URLsPerFile, (page-1)*sitemap.URLsPerFile)
+			if err != nil {
+				responses.Problem(w, err)
+				return
+			}
+			files = append(files, file{loc: fmt.Sprintf("%s/sitemaps/%s-%d.xml", base, name, page), lastMod: lastMod})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	index, err := sitemap.NewIndex(w)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		err = index.Add(f.loc, f.lastMod)
+		if err != nil {
+			return
+		}
+	}
+	index.Close()
+}
+
+// GetSitemap streams one numbered sitemap file of a section straight from the database
+func (server *Server) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	section, ok := sitemapSections[vars["section"]]
+	if !ok {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Sitemap not found"))
+		return
+	}
+	page, err := strconv.Atoi(vars["page"])
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	count, err := section.count(server.db(r))
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	if page < 1 || page > sitemap.Files(count) {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Sitemap not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	urls, err := sitemap.NewURLSet(w)
+	if err != nil {
+		return
+	}
+	base := baseURL(r) + APIPrefix
+	err = section.each(server.db(r), sitemap.URLsPerFile, (page-1)*sitemap.URLsPerFile, func(entry models.SitemapEntry) error {
+		return urls.Add(base+entry.Path, entry.LastMod)
+	})
+	if err != nil {
+		// The status is already sent, all that is left is to leave the document unterminated
+		logging.FromContext(r.Context()).Error("cannot write the sitemap", "section", section.name, "page", page, "error", err)
+		return
+	}
+	urls.Close()
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/syndication"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+func (server *Server) GetPostsFeed(w http.ResponseWriter, r *http.Request) {
+	feed := syndication.Feed{
+		Title:       "Latest posts",
+		Description: "The latest published posts",
+		Link:        baseURL(r) + APIPrefix + "/posts",
+	}
+	server.serveFeed(w, r, &feed, 0, "")
+}
+
+func (server *Server) GetUserPostsFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	uid, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+	user := models.User{}
+	userReceived, err := user.FindUserByID(server.db(r), uint32(uid))
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	feed := syndication.Feed{
+		Title:       "Posts by " + html.UnescapeString(userReceived.Nickname),
+		Description: "The latest posts by " + html.UnescapeString(userReceived.Nickname),
+		Link:        fmt.Sprintf("%s%s/users/%d", baseURL(r), APIPrefix, userReceived.ID),
+	}
+	server.serveFeed(w, r, &feed, userReceived.ID, "")
+}
+
+func (server *Server) GetTagPostsFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tag := models.Tag{}
+	tagReceived, err := tag.FindTagByName(server.db(r), strings.ToLower(vars["name"]))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			responses.ERROR(w, http.StatusNotFound, errors.New("Tag not found"))
+			return
+		}
+		responses.Problem(w, err)
+		return
+	}
+	feed := syndication.Feed{
+		Title:       "Posts tagged #" + tagReceived.Name,
+		Description: "The latest posts tagged #" + tagReceived.Name,
+		Link:        fmt.Sprintf("%s%s/tags/%s/posts", baseURL(r), APIPrefix, url.PathEscape(tagReceived.Name)),
+	}
+	server.serveFeed(w, r, &feed, 0, tagReceived.Name)
+}
+
+// serveFeed fills the feed with the latest posts matching the author and tag, and
+// writes it in the format of the url unless the client copy is still fresh
+func (server *Server) serveFeed(w http.ResponseWriter, r *http.Request, feed *syndication.Feed, authorID uint32, tag string) {
+	format := mux.Vars(r)["format"]
+	limit, err := pagination.LimitFromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post := models.Post{}
+	posts, err := post.FindRecentPosts(server.db(r), authorID, tag, limit)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	base := baseURL(r)
+	feed.FeedLink = base + r.URL.EscapedPath()
+	for _, p := range *posts {
+		feed.Items = append(feed.Items, syndication.Item{
+			// The IDs predate versions, readers would show every item again if they changed
+			ID:          fmt.Sprintf("%s/posts/%d", base, p.ID),
+			Title:       p.Title,
+			Link:        fmt.Sprintf("%s%s/posts/by-slug/%s", base, APIPrefix, p.Slug),
+			Summary:     p.Excerpt,
+			ContentHTML: p.ContentHTML,
+			AuthorName:  html.UnescapeString(p.Author.Nickname),
+			AuthorLink:  fmt.Sprintf("%s%s/users/%d", base, APIPrefix, p.AuthorID),
+			Tags:        p.Tags,
+			Published:   p.CreatedAt,
+			Updated:     p.UpdatedAt,
+		})
+	}
+
+	etag := feed.ETag(format)
+	updated := feed.Updated().UTC().Truncate(time.Second)
+	w.Header().Set("ETag", etag)
+	if !updated.IsZero() {
+		w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+	}
+	if notModified(r, etag, updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	body, err := feed.Encode(format)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", syndication.ContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// notModified applies the conditional request headers, If-None-Match wins over
+// If-Modified-Since when both are sent
+func notModified(r *http.Request, etag string, updated time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || updated.IsZero() {
+		return false
+	}
+	return !updated.After(since)
+}
+
+// baseURL is the scheme and host the request was sent to, as seen by the client
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
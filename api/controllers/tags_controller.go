@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/utils/pagination"
+)
+
+func (server *Server) GetTagPosts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	page, err := pagination.FromRequest(r)
+	if err != nil {
+		responses.ERROR(w, http.StatusBadRequest, err)
+		return
+	}
+
+	post := models.Post{}
+	posts, err := post.FindPostsByTag(server.db(r), vars["name"], page.Limit, page.Offset)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	err = server.attachMyReactions(r, *posts)
+	if err != nil {
+		responses.Problem(w, err)
+		return
+	}
+	responses.JSON(w, http.StatusOK, posts)
+}
@@ -10,15 +10,17 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/metrics"
 	"github.com/planutim/postgres-copy/api/models"
 	"github.com/planutim/postgres-copy/api/responses"
-	"github.com/planutim/postgres-copy/api/utils/formaterror"
 )
 
 func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		return
 	}
 	user := models.User{}
 	err = json.Unmarshal(body, &user)
@@ -29,17 +31,17 @@ func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 	user.Prepare()
 	err = user.Validate("")
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, err)
 		return
 	}
-	userCreated, err := user.SaveUser(server.DB)
+	userCreated, err := user.SaveUser(server.db(r))
 
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, database.TranslateError(err))
 		return
 	}
 
+	metrics.UsersCreated.Inc()
 	w.Header().Set("Location", fmt.Sprintf("%s%s/%d", r.Host, r.RequestURI, userCreated.ID))
 	responses.JSON(w, http.StatusCreated, userCreated)
 }
@@ -47,9 +49,9 @@ func (server *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 func (server *Server) GetUsers(w http.ResponseWriter, r *http.Request) {
 	user := models.User{}
 
-	users, err := user.FindAllUsers(server.DB)
+	users, err := user.FindAllUsers(server.db(r))
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.Problem(w, err)
 		return
 	}
 	responses.JSON(w, http.StatusOK, users)
@@ -64,9 +66,9 @@ func (server *Server) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := models.User{}
-	userGotten, err := user.FindUserByID(server.DB, uint32(uid))
+	userGotten, err := user.FindUserByID(server.db(r), uint32(uid))
 	if err != nil {
-		responses.ERROR(w, http.StatusBadRequest, err)
+		responses.Problem(w, err)
 		return
 	}
 	responses.JSON(w, http.StatusOK, userGotten)
@@ -99,19 +101,18 @@ func (server *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if tokenID != uint32(uid) {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+		responses.ERROR(w, http.StatusForbidden, errors.New("Forbidden"))
 		return
 	}
 	user.Prepare()
 	err = user.Validate("update")
 	if err != nil {
-		responses.ERROR(w, http.StatusUnprocessableEntity, err)
+		responses.Problem(w, err)
 		return
 	}
-	updatedUser, err := user.UpdateAUser(server.DB, uint32(uid))
+	updatedUser, err := user.UpdateAUser(server.db(r), uint32(uid))
 	if err != nil {
-		formattedError := formaterror.FormatError(err.Error())
-		responses.ERROR(w, http.StatusInternalServerError, formattedError)
+		responses.Problem(w, database.TranslateError(err))
 		return
 	}
 	responses.JSON(w, http.StatusOK, updatedUser)
@@ -131,15 +132,15 @@ func (server *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 		return
 	}
-	if tokenID != 0 && tokenID != uint32(uid) {
-		responses.ERROR(w, http.StatusUnauthorized, errors.New(http.StatusText(http.StatusUnauthorized)))
+	if tokenID != uint32(uid) {
+		responses.ERROR(w, http.StatusForbidden, errors.New("Forbidden"))
 		return
 	}
-	_, err = user.DeleteAUser(server.DB, uint32(uid))
+	_, err = user.DeleteAUser(server.db(r), uint32(uid))
 	if err != nil {
-		responses.ERROR(w, http.StatusInternalServerError, err)
+		responses.Problem(w, err)
 		return
 	}
 	w.Header().Set("Entity", fmt.Sprintf("%d", uid))
-	responses.JSON(w, http.StatusNoContent, "")
+	responses.NoContent(w)
 }
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// apiVersion is a version of the JSON API, served under its prefix. A new version
+// registers new handlers for the routes whose requests or responses change shape
+// and the handlers of the previous version for the others, so that clients of the
+// previous version keep getting the shapes they were written for.
+type apiVersion struct {
+	prefix string
+	routes func(s *Server, r *mux.Router)
+}
+
+// apiVersions are the versions served, oldest first
+var apiVersions = []apiVersion{
+	{prefix: "/api/v1", routes: routesV1},
+}
+
+// APIPrefix is the prefix of the current version, the one links point to
+var APIPrefix = apiVersions[len(apiVersions)-1].prefix
+
+// The routes of the first version were served at the root before versions existed,
+// they still are until SunsetAt
+var (
+	DeprecatedSince = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	SunsetAt        = time.Date(2027, time.April, 18, 0, 0, 0, 0, time.UTC)
+)
+
+// DeprecatedRoutes names the route holding the deprecated routes
+const DeprecatedRoutes = "deprecated"
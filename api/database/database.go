@@ -0,0 +1,79 @@
+// Package database opens the connection pool of every supported database. What
+// differs from one database to another lives behind the Dialect of its driver.
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/config"
+
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+// Dialect is what the API needs to know about a database beyond what gorm handles
+type Dialect interface {
+	// Name is the driver name, as gorm and database/sql know it
+	Name() string
+	// DSN is the data source name connecting to the configured database
+	DSN(cfg config.DBConfig) string
+	// SchemaMigrationsTable creates the table recording the applied migrations
+	SchemaMigrationsTable() string
+	// Pool adjusts the pool settings to what the database supports
+	Pool(cfg config.DBConfig) config.DBConfig
+	// Violation tells which constraint err violated, when it is an error of the
+	// driver reporting a constraint violation
+	Violation(err error) (*Violation, bool)
+}
+
+var dialects = map[string]Dialect{}
+
+func register(d Dialect) {
+	dialects[d.Name()] = d
+}
+
+// Drivers are the names of the supported drivers, sorted
+func Drivers() []string {
+	names := []string{}
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup finds the dialect of a driver
+func Lookup(driver string) (Dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("database driver %q is not supported, use one of %s", driver, strings.Join(Drivers(), ", "))
+	}
+	return d, nil
+}
+
+// DialectOf is the dialect of an open connection
+func DialectOf(db *gorm.DB) (Dialect, error) {
+	return Lookup(db.Dialect().GetName())
+}
+
+// Open connects to the configured database and sizes its pool. Nothing is done to
+// the schema, see the migrations package.
+func Open(cfg config.DBConfig) (*gorm.DB, error) {
+	d, err := Lookup(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(d.Name(), d.DSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the %s database: %v", d.Name(), err)
+	}
+	cfg = d.Pool(cfg)
+	db.DB().SetMaxOpenConns(cfg.MaxOpenConns)
+	db.DB().SetMaxIdleConns(cfg.MaxIdleConns)
+	db.DB().SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	return db, nil
+}
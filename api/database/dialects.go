@@ -0,0 +1,78 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/planutim/postgres-copy/api/config"
+)
+
+func init() {
+	register(postgres{})
+	register(mysql{})
+	register(sqlite{})
+}
+
+type postgres struct{}
+
+func (postgres) Name() string { return "postgres" }
+
+func (postgres) DSN(cfg config.DBConfig) string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", cfg.Host, cfg.Port, cfg.User, cfg.Name, cfg.Password)
+}
+
+func (postgres) SchemaMigrationsTable() string {
+	return "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name varchar(255) NOT NULL, applied_at timestamp with time zone NOT NULL)"
+}
+
+func (postgres) Pool(cfg config.DBConfig) config.DBConfig { return cfg }
+
+type mysql struct{}
+
+func (mysql) Name() string { return "mysql" }
+
+func (mysql) DSN(cfg config.DBConfig) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Name)
+}
+
+func (mysql) SchemaMigrationsTable() string {
+	return "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name varchar(255) NOT NULL, applied_at DATETIME NOT NULL)"
+}
+
+func (mysql) Pool(cfg config.DBConfig) config.DBConfig { return cfg }
+
+// sqlite keeps the database in the file named by DB_NAME, or in memory when the
+// name is :memory:
+type sqlite struct{}
+
+func (sqlite) Name() string { return "sqlite3" }
+
+func (sqlite) DSN(cfg config.DBConfig) string {
+	// Concurrent writers wait for the lock instead of failing right away
+	params := url.Values{"_busy_timeout": {"5000"}}
+	if strings.Contains(cfg.Name, "?") {
+		return cfg.Name + "&" + params.Encode()
+	}
+	return cfg.Name + "?" + params.Encode()
+}
+
+func (sqlite) SchemaMigrationsTable() string {
+	return "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint PRIMARY KEY, name varchar(255) NOT NULL, applied_at datetime NOT NULL)"
+}
+
+func (sqlite) Pool(cfg config.DBConfig) config.DBConfig {
+	if InMemory(cfg) {
+		// Every connection to :memory: opens a new empty database, so the pool
+		// keeps exactly one connection for as long as the process runs
+		cfg.MaxOpenConns = 1
+		cfg.MaxIdleConns = 1
+		cfg.ConnMaxLifetime = 0
+	}
+	return cfg
+}
+
+// InMemory tells whether the configuration names an in-memory SQLite database
+func InMemory(cfg config.DBConfig) bool {
+	return cfg.Driver == "sqlite3" && strings.Contains(cfg.Name, ":memory:")
+}
@@ -0,0 +1,282 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+// ViolationKind is the kind of constraint a statement violated
+type ViolationKind int
+
+const (
+	UniqueViolation ViolationKind = iota + 1
+	// ForeignKeyViolation is a row referencing a row that does not exist
+	ForeignKeyViolation
+	// ReferencedViolation is the deletion of a row other rows still reference
+	ReferencedViolation
+	NotNullViolation
+	CheckViolation
+	TooLongViolation
+)
+
+// Violation is a constraint violation reported by a database, as far as the
+// driver tells: Constraint and Columns may be empty
+type Violation struct {
+	Kind       ViolationKind
+	Table      string
+	Constraint string
+	Columns    []string
+}
+
+// uniqueIndexes are the columns of the named unique indexes of the migrations. MySQL
+// only reports the name of the index a duplicate entry violates.
+var uniqueIndexes = map[string][]string{
+	"idx_posts_author_title":       {"title", "author_id"},
+	"idx_posts_slug":               {"slug"},
+	"idx_reactions_post_user_type": {"post_id", "user_id", "type"},
+	"idx_reading_lists_user_name":  {"user_id", "name"},
+	"idx_bookmarks_user_post":      {"user_id", "post_id"},
+}
+
+// TranslateError turns a constraint violation into an error clients can act upon:
+// a conflict for duplicates and rows still referenced, a validation error naming
+// the field otherwise. Any other error is returned unchanged.
+func TranslateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	cause := err
+	// gorm collects the errors of a statement and its callbacks, the first one failed it
+	if errs, ok := err.(gorm.Errors); ok && len(errs) > 0 {
+		cause = errs[0]
+	}
+	for _, d := range dialects {
+		if v, ok := d.Violation(cause); ok {
+			return v.Err(err)
+		}
+	}
+	return err
+}
+
+// Err is the error reported to clients, cause stays reachable through errors.Is
+// and errors.As
+func (v *Violation) Err(cause error) error {
+	var fields []apperrors.FieldError
+	add := func(column, message string) {
+		fields = append(fields, apperrors.FieldError{Field: column, Message: message})
+	}
+	kind := apperrors.Validation
+	message := ""
+	switch v.Kind {
+	case UniqueViolation:
+		kind = apperrors.Conflict
+		message = "Already Exists"
+		for _, column := range visibleColumns(v.Columns) {
+			add(column, humanize(column)+" Already Taken")
+		}
+	case ReferencedViolation:
+		kind = apperrors.Conflict
+		message = "Still Referenced"
+	case ForeignKeyViolation:
+		message = "Invalid Reference"
+		for _, column := range v.Columns {
+			add(column, "Unknown "+humanize(column))
+		}
+	case NotNullViolation:
+		message = "Required Value"
+		for _, column := range v.Columns {
+			add(column, "Required "+humanize(column))
+		}
+	case TooLongViolation:
+		message = "Value Too Long"
+		for _, column := range v.Columns {
+			add(column, humanize(column)+" Too Long")
+		}
+	case CheckViolation:
+		// A check constraint may involve several columns, none of them is blamed
+		message = "Invalid Value"
+	}
+	if len(fields) > 0 {
+		messages := make([]string, len(fields))
+		for i, f := range fields {
+			messages[i] = f.Message
+		}
+		message = strings.Join(messages, "; ")
+	}
+	return &apperrors.Error{Kind: kind, Message: message, Fields: fields, Err: cause}
+}
+
+// visibleColumns drops the ID columns scoping a unique index to a user or a post,
+// clients can only change the other ones
+func visibleColumns(columns []string) []string {
+	visible := []string{}
+	for _, column := range columns {
+		if !strings.HasSuffix(column, "_id") {
+			visible = append(visible, column)
+		}
+	}
+	if len(visible) == 0 {
+		return columns
+	}
+	return visible
+}
+
+// humanize names a column in messages, author_id becomes Author
+func humanize(column string) string {
+	words := strings.Split(strings.TrimSuffix(column, "_id"), "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// columnList splits a list of columns, dropping the quotes and table names around them
+func columnList(list string) []string {
+	columns := []string{}
+	for _, c := range strings.Split(list, ",") {
+		c = strings.Trim(strings.TrimSpace(c), "`\"")
+		if i := strings.LastIndex(c, "."); i >= 0 {
+			c = c[i+1:]
+		}
+		if c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// indexColumns are the columns of a unique index, a column declared UNIQUE gets an
+// index of its own name
+func indexColumns(index string) []string {
+	if i := strings.LastIndex(index, "."); i >= 0 {
+		index = index[i+1:]
+	}
+	if columns, ok := uniqueIndexes[index]; ok {
+		return columns
+	}
+	return []string{index}
+}
+
+var postgresKey = regexp.MustCompile(`^Key \(([^)]*)\)=`)
+
+// Violation reads the SQLSTATE of the error, the columns of a key are in its detail
+func (postgres) Violation(err error) (*Violation, bool) {
+	var e *pq.Error
+	if !errors.As(err, &e) {
+		return nil, false
+	}
+	v := &Violation{Table: e.Table, Constraint: e.Constraint}
+	if m := postgresKey.FindStringSubmatch(e.Detail); m != nil {
+		v.Columns = columnList(m[1])
+	}
+	switch e.Code {
+	case "23505":
+		v.Kind = UniqueViolation
+		if len(v.Columns) == 0 && e.Constraint != "" {
+			v.Columns = indexColumns(e.Constraint)
+		}
+	case "23503":
+		v.Kind = ForeignKeyViolation
+		if strings.Contains(e.Detail, "is still referenced") {
+			v.Kind = ReferencedViolation
+		}
+	case "23502":
+		v.Kind = NotNullViolation
+		v.Columns = columnList(e.Column)
+	case "23514":
+		v.Kind = CheckViolation
+	case "22001":
+		v.Kind = TooLongViolation
+		v.Columns = columnList(e.Column)
+	default:
+		return nil, false
+	}
+	return v, true
+}
+
+var (
+	mysqlDuplicate  = regexp.MustCompile(`for key '([^']*)'`)
+	mysqlColumn     = regexp.MustCompile(`[Cc]olumn '([^']*)'`)
+	mysqlForeignKey = regexp.MustCompile("CONSTRAINT `([^`]*)` FOREIGN KEY \\(([^)]*)\\)")
+	mysqlCheck      = regexp.MustCompile(`[Cc]heck constraint '([^']*)'`)
+)
+
+// Violation reads the error number, the messages name the key or the column
+func (mysql) Violation(err error) (*Violation, bool) {
+	var e *mysqlDriver.MySQLError
+	if !errors.As(err, &e) {
+		return nil, false
+	}
+	v := &Violation{}
+	switch e.Number {
+	case 1062:
+		v.Kind = UniqueViolation
+		if m := mysqlDuplicate.FindStringSubmatch(e.Message); m != nil {
+			v.Constraint = m[1]
+			v.Columns = indexColumns(m[1])
+		}
+	case 1451, 1452:
+		v.Kind = ForeignKeyViolation
+		if e.Number == 1451 {
+			v.Kind = ReferencedViolation
+		}
+		if m := mysqlForeignKey.FindStringSubmatch(e.Message); m != nil {
+			v.Constraint = m[1]
+			v.Columns = columnList(m[2])
+		}
+	case 1048, 1364:
+		v.Kind = NotNullViolation
+	case 1406:
+		v.Kind = TooLongViolation
+	case 3819:
+		v.Kind = CheckViolation
+		if m := mysqlCheck.FindStringSubmatch(e.Message); m != nil {
+			v.Constraint = m[1]
+		}
+	default:
+		return nil, false
+	}
+	if m := mysqlColumn.FindStringSubmatch(e.Message); m != nil && v.Columns == nil {
+		v.Columns = columnList(m[1])
+	}
+	return v, true
+}
+
+// Violation reads the extended result code, the message lists the columns after
+// a colon, such as UNIQUE constraint failed: posts.title, posts.author_id
+func (sqlite) Violation(err error) (*Violation, bool) {
+	var e sqlite3.Error
+	if !errors.As(err, &e) || e.Code != sqlite3.ErrConstraint {
+		return nil, false
+	}
+	v := &Violation{}
+	detail := ""
+	if i := strings.Index(e.Error(), ": "); i >= 0 {
+		detail = e.Error()[i+2:]
+	}
+	switch e.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		v.Kind = UniqueViolation
+		v.Columns = columnList(detail)
+	case sqlite3.ErrConstraintForeignKey:
+		v.Kind = ForeignKeyViolation
+	case sqlite3.ErrConstraintNotNull:
+		v.Kind = NotNullViolation
+		v.Columns = columnList(detail)
+	case sqlite3.ErrConstraintCheck:
+		v.Kind = CheckViolation
+		v.Constraint = detail
+	default:
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,36 @@
+package database
+
+import "github.com/jinzhu/gorm"
+
+// Hook is called with the scope of a statement and the operation it belongs to:
+// create, query, row_query, update or delete
+type Hook func(scope *gorm.Scope, operation string)
+
+// RegisterHooks calls before ahead of every statement gorm runs on db and after it
+// once the statement is over, errors included. The callbacks are registered as
+// name:before_<operation> and name:after_<operation>.
+func RegisterHooks(db *gorm.DB, name string, before, after Hook) {
+	callbacks := db.Callback()
+	// Every registration needs its own processor, Before and After modify it in place
+	operations := []struct {
+		name      string
+		processor func() *gorm.CallbackProcessor
+		first     string
+		last      string
+	}{
+		{"create", callbacks.Create, "gorm:begin_transaction", "gorm:commit_or_rollback_transaction"},
+		{"query", callbacks.Query, "gorm:query", "gorm:after_query"},
+		{"row_query", callbacks.RowQuery, "gorm:row_query", "gorm:row_query"},
+		{"update", callbacks.Update, "gorm:begin_transaction", "gorm:commit_or_rollback_transaction"},
+		{"delete", callbacks.Delete, "gorm:begin_transaction", "gorm:commit_or_rollback_transaction"},
+	}
+	for _, op := range operations {
+		operation := op.name
+		op.processor().Before(op.first).Register(name+":before_"+operation, func(scope *gorm.Scope) {
+			before(scope, operation)
+		})
+		op.processor().After(op.last).Register(name+":after_"+operation, func(scope *gorm.Scope) {
+			after(scope, operation)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// GormLogger adapts slog to the logger of gorm. Statements slower than the
+// threshold are warnings, the others are only logged at the debug level. The
+// values bound to a statement are never logged, they include password hashes and
+// emails.
+type GormLogger struct {
+	Logger        *slog.Logger
+	SlowThreshold time.Duration
+}
+
+// Print receives what gorm logs: ("sql", source, duration, statement, values, rows)
+// for statements, ("log" or "error", source, error) for errors and ("info" or
+// "warning", message) for its own notices
+func (g GormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		return
+	}
+	source, _ := values[1].(string)
+	switch values[0] {
+	case "info":
+		g.Logger.Debug("gorm", slog.String("message", strings.TrimPrefix(source, "[info] ")))
+	case "warning":
+		g.Logger.Warn("gorm", slog.String("message", strings.TrimPrefix(source, "[warning] ")))
+	case "sql":
+		if len(values) < 6 {
+			return
+		}
+		duration, _ := values[2].(time.Duration)
+		slow := g.SlowThreshold > 0 && duration >= g.SlowThreshold
+		if !slow && !g.Logger.Enabled(context.Background(), slog.LevelDebug) {
+			return
+		}
+		statement, _ := values[3].(string)
+		rows, _ := values[5].(int64)
+		attrs := []any{
+			slog.String("source", source),
+			slog.String("sql", statement),
+			slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+			slog.Int64("rows", rows),
+		}
+		if slow {
+			g.Logger.Warn("slow query", attrs...)
+			return
+		}
+		g.Logger.Debug("query", attrs...)
+	case "log", "error":
+		if len(values) < 3 {
+			return
+		}
+		err, ok := values[2].(error)
+		if !ok {
+			return
+		}
+		g.Logger.Error("query failed", slog.String("source", source), slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,85 @@
+// Package logging writes the structured JSON logs of the API. Loggers taken from a
+// request context carry the fields of that request, such as its ID, route and user.
+//
+// Nothing logged here may contain a secret: no passwords, tokens, claims, request
+// bodies or query parameters, and no SQL bind values.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(strings.ToLower(level)))
+	if err != nil {
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+	}
+	return l, nil
+}
+
+// New is a JSON logger writing the records of level and above to w
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Setup makes a JSON logger writing to w the default one, for slog and for the
+// standard log package
+func Setup(w io.Writer, level string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(New(w, l))
+	return nil
+}
+
+type fieldsKey struct{}
+
+// fields are the attributes of a request, filled in as the request goes through the
+// middlewares
+type fields struct {
+	mu    sync.Mutex
+	attrs []any
+}
+
+// WithFields starts a set of request fields in ctx, or adds to the set already there
+func WithFields(ctx context.Context, attrs ...any) context.Context {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.add(attrs...)
+		return ctx
+	}
+	return context.WithValue(ctx, fieldsKey{}, &fields{attrs: attrs})
+}
+
+// AddFields adds attributes to the request fields of ctx, the request and every
+// logger taken from its context afterwards see them. Without fields in ctx it does
+// nothing.
+func AddFields(ctx context.Context, attrs ...any) {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.add(attrs...)
+	}
+}
+
+func (f *fields) add(attrs ...any) {
+	f.mu.Lock()
+	f.attrs = append(f.attrs, attrs...)
+	f.mu.Unlock()
+}
+
+// FromContext is the default logger with the request fields of ctx
+func FromContext(ctx context.Context) *slog.Logger {
+	f, ok := ctx.Value(fieldsKey{}).(*fields)
+	if !ok {
+		return slog.Default()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slog.Default().With(f.attrs...)
+}
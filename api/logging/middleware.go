@@ -0,0 +1,42 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/requestid"
+	"github.com/planutim/postgres-copy/api/utils/route"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware gives every request of a mux router its fields, the request and trace
+// IDs and the route template, and logs it once served. Only the path is logged,
+// query strings may carry tokens.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		attrs := []any{slog.String("route", route.Template(r))}
+		if id := requestid.FromContext(r.Context()); id != "" {
+			attrs = append(attrs, slog.String("request_id", id))
+		}
+		if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+		ctx := WithFields(r.Context(), attrs...)
+		recorder := route.NewRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if recorder.Status() >= 500 {
+			level = slog.LevelError
+		}
+		FromContext(ctx).Log(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", recorder.Status()),
+			slog.Int("bytes", recorder.Bytes()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time taken by the database queries, by operation and table.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_query_errors_total",
+		Help: "Failed database queries, by operation and table. A query finding no record is not a failure.",
+	}, []string{"operation", "table"})
+)
+
+const startKey = "metrics:start"
+
+// InstrumentDB measures the queries gorm runs on db and exposes the statistics of
+// its connection pool, labelled with name. Call it once per connection pool.
+func InstrumentDB(db *gorm.DB, name string) error {
+	database.RegisterHooks(db, "metrics", func(scope *gorm.Scope, operation string) {
+		scope.InstanceSet(startKey, time.Now())
+	}, observe)
+	return Registry.Register(collectors.NewDBStatsCollector(db.DB(), name))
+}
+
+func observe(scope *gorm.Scope, operation string) {
+	value, ok := scope.InstanceGet(startKey)
+	if !ok {
+		return
+	}
+	start, ok := value.(time.Time)
+	if !ok {
+		return
+	}
+	table := scope.TableName()
+	queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+		queryErrors.WithLabelValues(operation, table).Inc()
+	}
+}
@@ -0,0 +1,83 @@
+// Package metrics exposes the Prometheus metrics of the API: HTTP requests,
+// database queries and pool, and business events.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/utils/route"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric of the API, apart from the default registry so that
+// imported packages cannot add their own
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests served, by method, route template and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time to serve HTTP requests, by method, route template and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// UsersCreated counts the users signing up
+	UsersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "users_created_total",
+		Help: "Users created through the API.",
+	})
+
+	// PostsPublished counts the posts created published or going from draft to published
+	PostsPublished = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "posts_published_total",
+		Help: "Posts published through the API.",
+	})
+
+	// LoginFailures counts the rejected logins, whatever the reason
+	LoginFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "login_failures_total",
+		Help: "Rejected login attempts.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		queryDuration,
+		queryErrors,
+		UsersCreated,
+		PostsPublished,
+		LoginFailures,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware measures the requests of a mux router. Requests are labelled by the
+// template of the matched route, so that /posts/1 and /posts/2 share their series;
+// requests matching no route are labelled unmatched.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := route.NewRecorder(w)
+		next.ServeHTTP(recorder, r)
+
+		labels := prometheus.Labels{"method": r.Method, "route": route.Template(r), "status": strconv.Itoa(recorder.Status())}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
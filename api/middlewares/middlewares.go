@@ -2,9 +2,12 @@ package middlewares
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/logging"
 	"github.com/planutim/postgres-copy/api/responses"
 )
 
@@ -22,6 +25,25 @@ func SetMiddlewareAuthentication(next http.HandlerFunc) http.HandlerFunc {
 			responses.ERROR(w, http.StatusUnauthorized, errors.New("Unauthorized"))
 			return
 		}
+		// Only the user ID is logged, never the token or its other claims
+		if uid, err := auth.ExtractTokenID(r); err == nil {
+			logging.AddFields(r.Context(), "user_id", uid)
+		}
 		next(w, r)
 	}
 }
+
+// SetMiddlewareDeprecation marks the responses of routes replaced by the same route
+// under the successor prefix, with the Deprecation (RFC 9745) and Sunset (RFC 8594)
+// headers and a link to the successor
+func SetMiddlewareDeprecation(successor string, since, sunset time.Time) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", since.Unix()))
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successor, r.URL.EscapedPath()))
+			logging.AddFields(r.Context(), "deprecated", true)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
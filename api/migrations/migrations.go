@@ -0,0 +1,327 @@
+// Package migrations applies the numbered SQL migrations of the schema and keeps
+// track of them in the schema_migrations table.
+//
+// Every dialect has its own directory of NNNN_name.up.sql and NNNN_name.down.sql
+// files, all dialects share the same versions. Statements in a file are separated
+// by a semicolon at the end of a line. A version can also have a step, in Go, for
+// the data changes SQL cannot express the same way on every dialect.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/database"
+)
+
+//go:embed postgres mysql sqlite3
+var files embed.FS
+
+// Dialects are the databases migrations are written for, named like gorm names them
+var Dialects = []string{"postgres", "mysql", "sqlite3"}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+	// Step runs after Up in the same transaction, Down does not revert it
+	Step func(tx *gorm.DB) error
+}
+
+// SchemaMigration is the row recorded for every applied migration
+type SchemaMigration struct {
+	Version   uint64    `gorm:"primary_key;auto_increment:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Status tells whether a known migration is applied, AppliedAt is nil when it is not
+type Status struct {
+	Version   uint64     `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New prepares the migrations of the dialect db is connected to and creates the
+// schema_migrations table when missing
+func New(db *gorm.DB) (*Migrator, error) {
+	dialect, err := database.DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(dialect.Name())
+	if err != nil {
+		return nil, err
+	}
+	err = db.Exec(dialect.SchemaMigrationsTable()).Error
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Open prepares the migrations of the dialect db is connected to without touching
+// the schema, for the checks that only read the status. It fails when the
+// schema_migrations table does not exist.
+func Open(db *gorm.DB) (*Migrator, error) {
+	dialect, err := database.DialectOf(db)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := Load(dialect.Name())
+	if err != nil {
+		return nil, err
+	}
+	if !db.HasTable(&SchemaMigration{}) {
+		return nil, errors.New("The schema_migrations table does not exist")
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads the embedded migrations of a dialect, in version order
+func Load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[uint64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		body, err := fs.ReadFile(files, dialect+"/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		m.Step = steps[m.Version]
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrations are the known migrations, in version order
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+func (m *Migrator) applied() (map[uint64]SchemaMigration, error) {
+	rows := []SchemaMigration{}
+	err := m.db.Order("version").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[uint64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	status := []Status{}
+	for _, migration := range m.migrations {
+		s := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+// Pending counts the migrations Up would apply
+func (m *Migrator) Pending() (int, error) {
+	status, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration in version order, each in its own transaction,
+// and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			err := execute(tx, migration.Up)
+			if err != nil {
+				return err
+			}
+			if migration.Step != nil {
+				err = migration.Step(tx)
+				if err != nil {
+					return err
+				}
+			}
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the last steps applied migrations, latest first, and returns how many
+// were reverted
+func (m *Migrator) Down(steps int) (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err = m.db.Transaction(func(tx *gorm.DB) error {
+			err := execute(tx, migration.Down)
+			if err != nil {
+				return err
+			}
+			return tx.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func execute(tx *gorm.DB, script string) error {
+	for _, statement := range split(script) {
+		err := tx.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// split cuts a script into statements at the semicolons ending a line, leaving out
+// comment lines
+func split(script string) []string {
+	statements := []string{}
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Create writes empty up and down files for a new migration in the directory of
+// every dialect under dir, numbered after the latest existing one
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("Required Migration Name")
+	}
+	var latest uint64
+	for _, dialect := range Dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			match := fileName.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+			version, _ := strconv.ParseUint(match[1], 10, 64)
+			if version > latest {
+				latest = version
+			}
+		}
+	}
+	created := []string{}
+	for _, dialect := range Dialects {
+		err := os.MkdirAll(filepath.Join(dir, dialect), 0755)
+		if err != nil {
+			return created, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s.%s.sql", latest+1, name, direction))
+			err = os.WriteFile(path, []byte(fmt.Sprintf("-- %s %s migration for %s\n", name, direction, dialect)), 0644)
+			if err != nil {
+				return created, err
+			}
+			created = append(created, path)
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,21 @@
+-- The schema of the releases that created their tables with AutoMigrate, before
+-- there were migrations. Every statement is conditional, so applying it to one of
+-- their databases only records the version, the next migrations upgrade it.
+
+CREATE TABLE IF NOT EXISTS users (
+    id int unsigned AUTO_INCREMENT PRIMARY KEY,
+    nickname varchar(255) NOT NULL UNIQUE,
+    email varchar(100) NOT NULL UNIQUE,
+    password varchar(100) NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    title varchar(255) NOT NULL UNIQUE,
+    content varchar(255) NOT NULL,
+    author_id int unsigned NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,8 @@
+ALTER TABLE posts DROP COLUMN angry_count;
+ALTER TABLE posts DROP COLUMN sad_count;
+ALTER TABLE posts DROP COLUMN wow_count;
+ALTER TABLE posts DROP COLUMN laugh_count;
+ALTER TABLE posts DROP COLUMN love_count;
+ALTER TABLE posts DROP COLUMN like_count;
+ALTER TABLE posts DROP COLUMN status;
+ALTER TABLE posts DROP COLUMN excerpt;
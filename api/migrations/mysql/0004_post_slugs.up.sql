@@ -0,0 +1,11 @@
+-- The slug of every post, filled in for the existing posts by the step of this
+-- version, and the slugs posts had before their title changed
+
+ALTER TABLE posts ADD COLUMN slug varchar(255) NOT NULL DEFAULT '';
+
+CREATE TABLE post_slugs (
+    slug varchar(255) PRIMARY KEY,
+    post_id bigint unsigned NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_post_slugs_post_id (post_id)
+);
@@ -0,0 +1 @@
+DROP INDEX idx_posts_slug ON posts;
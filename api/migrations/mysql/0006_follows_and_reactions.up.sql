@@ -0,0 +1,17 @@
+-- Users follow each other and react to posts
+
+CREATE TABLE follows (
+    follower_id int unsigned NOT NULL,
+    following_id int unsigned NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, following_id)
+);
+
+CREATE TABLE reactions (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    post_id bigint unsigned NOT NULL,
+    user_id int unsigned NOT NULL,
+    type varchar(20) NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_reactions_post_user_type (post_id, user_id, type)
+);
@@ -0,0 +1,20 @@
+-- Users bookmark posts, optionally in named reading lists
+
+CREATE TABLE reading_lists (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id int unsigned NOT NULL,
+    name varchar(100) NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_reading_lists_user_name (user_id, name)
+);
+
+CREATE TABLE bookmarks (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id int unsigned NOT NULL,
+    post_id bigint unsigned NOT NULL,
+    reading_list_id bigint unsigned NOT NULL DEFAULT 0,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE INDEX idx_bookmarks_user_post (user_id, post_id),
+    INDEX idx_bookmarks_reading_list_id (reading_list_id)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS mentions;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
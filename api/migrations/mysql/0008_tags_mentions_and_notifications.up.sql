@@ -0,0 +1,33 @@
+-- The hashtags and mentions parsed from posts, and the notifications of mentions
+
+CREATE TABLE tags (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    name varchar(100) NOT NULL UNIQUE,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE post_tags (
+    post_id bigint unsigned NOT NULL,
+    tag_id bigint unsigned NOT NULL,
+    PRIMARY KEY (post_id, tag_id),
+    INDEX idx_post_tags_tag_id (tag_id)
+);
+
+CREATE TABLE mentions (
+    post_id bigint unsigned NOT NULL,
+    user_id int unsigned NOT NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, user_id),
+    INDEX idx_mentions_user_id (user_id)
+);
+
+CREATE TABLE notifications (
+    id bigint unsigned AUTO_INCREMENT PRIMARY KEY,
+    user_id int unsigned NOT NULL,
+    actor_id int unsigned NOT NULL,
+    post_id bigint unsigned NOT NULL,
+    kind varchar(20) NOT NULL,
+    read_at DATETIME NULL,
+    created_at DATETIME NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_notifications_user_id (user_id)
+);
@@ -0,0 +1,6 @@
+-- Titles are unique per author instead of across every post, and the content
+-- holds Markdown of any length
+
+ALTER TABLE posts ALTER COLUMN content TYPE text;
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_title_key;
+CREATE UNIQUE INDEX idx_posts_author_title ON posts (title, author_id);
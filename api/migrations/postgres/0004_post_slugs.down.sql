@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS post_slugs;
+ALTER TABLE posts DROP COLUMN slug;
@@ -0,0 +1,3 @@
+-- Every post has a slug of its own now that 0004 filled them in
+
+CREATE UNIQUE INDEX idx_posts_slug ON posts (slug);
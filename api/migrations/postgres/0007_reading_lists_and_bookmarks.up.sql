@@ -0,0 +1,20 @@
+-- Users bookmark posts, optionally in named reading lists
+
+CREATE TABLE reading_lists (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    name varchar(100) NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_reading_lists_user_name ON reading_lists (user_id, name);
+
+CREATE TABLE bookmarks (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    reading_list_id bigint NOT NULL DEFAULT 0,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_bookmarks_user_post ON bookmarks (user_id, post_id);
+CREATE INDEX idx_bookmarks_reading_list_id ON bookmarks (reading_list_id);
@@ -0,0 +1,33 @@
+-- The hashtags and mentions parsed from posts, and the notifications of mentions
+
+CREATE TABLE tags (
+    id bigserial PRIMARY KEY,
+    name varchar(100) NOT NULL UNIQUE,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE post_tags (
+    post_id bigint NOT NULL,
+    tag_id bigint NOT NULL,
+    PRIMARY KEY (post_id, tag_id)
+);
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+
+CREATE TABLE mentions (
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, user_id)
+);
+CREATE INDEX idx_mentions_user_id ON mentions (user_id);
+
+CREATE TABLE notifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL,
+    actor_id bigint NOT NULL,
+    post_id bigint NOT NULL,
+    kind varchar(20) NOT NULL,
+    read_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_notifications_user_id ON notifications (user_id);
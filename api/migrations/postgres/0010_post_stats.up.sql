@@ -0,0 +1,6 @@
+-- The word count and reading time of posts, stored when they are saved instead of
+-- rendered on every load. The step of this version fills them in for the existing
+-- posts.
+
+ALTER TABLE posts ADD COLUMN word_count integer NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN reading_time_minutes integer NOT NULL DEFAULT 0;
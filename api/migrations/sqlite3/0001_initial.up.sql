@@ -0,0 +1,21 @@
+-- The schema of the releases that created their tables with AutoMigrate, before
+-- there were migrations. Every statement is conditional, so applying it to one of
+-- their databases only records the version, the next migrations upgrade it.
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    nickname varchar(255) NOT NULL UNIQUE,
+    email varchar(100) NOT NULL UNIQUE,
+    password varchar(100) NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title varchar(255) NOT NULL UNIQUE,
+    content varchar(255) NOT NULL,
+    author_id integer NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
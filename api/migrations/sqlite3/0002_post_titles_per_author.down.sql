@@ -0,0 +1,5 @@
+-- The titles stay without a global unique constraint and the content stays text:
+-- restoring them would fail on the posts sharing a title or longer than 255
+-- characters, written since.
+
+DROP INDEX IF EXISTS idx_posts_author_title;
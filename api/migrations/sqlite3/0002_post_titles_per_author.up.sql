@@ -0,0 +1,18 @@
+-- Titles are unique per author instead of across every post, and the content
+-- holds Markdown of any length
+--
+-- SQLite cannot drop the unique constraint of a column, the table is rebuilt
+
+CREATE TABLE posts_rebuilt (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title varchar(255) NOT NULL,
+    content text NOT NULL,
+    author_id integer NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO posts_rebuilt (id, title, content, author_id, created_at, updated_at)
+SELECT id, title, content, author_id, created_at, updated_at FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_rebuilt RENAME TO posts;
+CREATE UNIQUE INDEX idx_posts_author_title ON posts (title, author_id);
@@ -0,0 +1,14 @@
+-- SQLite before 3.35 cannot drop columns, the table is rebuilt without them
+CREATE TABLE posts_rebuilt (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title varchar(255) NOT NULL,
+    content text NOT NULL,
+    author_id integer NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO posts_rebuilt (id, title, content, author_id, created_at, updated_at)
+SELECT id, title, content, author_id, created_at, updated_at FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_rebuilt RENAME TO posts;
+CREATE UNIQUE INDEX idx_posts_author_title ON posts (title, author_id);
@@ -0,0 +1,10 @@
+-- Drafts, excerpts and the reaction counters of posts
+
+ALTER TABLE posts ADD COLUMN excerpt varchar(500) NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN status varchar(20) NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN like_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN love_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN laugh_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN wow_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN sad_count bigint NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN angry_count bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- Users follow each other and react to posts
+
+CREATE TABLE follows (
+    follower_id bigint NOT NULL,
+    following_id bigint NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, following_id)
+);
+
+CREATE TABLE reactions (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    post_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    type varchar(20) NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX idx_reactions_post_user_type ON reactions (post_id, user_id, type);
@@ -0,0 +1,2 @@
+-- Escaping the titles and content again would also escape the posts written since
+-- as they were meant, they stay unescaped.
@@ -0,0 +1,7 @@
+-- The first release stored titles and content HTML escaped. They are stored as
+-- written now, every output escapes them for its own format. The replacements
+-- undo html.EscapeString, &amp; comes last.
+
+UPDATE posts SET
+    title = REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(title, '&lt;', '<'), '&gt;', '>'), '&#39;', ''''), '&#34;', '"'), '&amp;', '&'),
+    content = REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(content, '&lt;', '<'), '&gt;', '>'), '&#39;', ''''), '&#34;', '"'), '&amp;', '&');
@@ -0,0 +1,24 @@
+-- SQLite before 3.35 cannot drop columns, the table is rebuilt without them
+CREATE TABLE posts_rebuilt (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    title varchar(255) NOT NULL,
+    content text NOT NULL,
+    author_id integer NOT NULL,
+    created_at datetime DEFAULT CURRENT_TIMESTAMP,
+    updated_at datetime DEFAULT CURRENT_TIMESTAMP,
+    excerpt varchar(500) NOT NULL DEFAULT '',
+    status varchar(20) NOT NULL DEFAULT 'published',
+    like_count bigint NOT NULL DEFAULT 0,
+    love_count bigint NOT NULL DEFAULT 0,
+    laugh_count bigint NOT NULL DEFAULT 0,
+    wow_count bigint NOT NULL DEFAULT 0,
+    sad_count bigint NOT NULL DEFAULT 0,
+    angry_count bigint NOT NULL DEFAULT 0,
+    slug varchar(255) NOT NULL DEFAULT ''
+);
+INSERT INTO posts_rebuilt (id, title, content, author_id, created_at, updated_at, excerpt, status, like_count, love_count, laugh_count, wow_count, sad_count, angry_count, slug)
+SELECT id, title, content, author_id, created_at, updated_at, excerpt, status, like_count, love_count, laugh_count, wow_count, sad_count, angry_count, slug FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_rebuilt RENAME TO posts;
+CREATE UNIQUE INDEX idx_posts_author_title ON posts (title, author_id);
+CREATE UNIQUE INDEX idx_posts_slug ON posts (slug);
@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"fmt"
+	"html"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/render"
+	"github.com/planutim/postgres-copy/api/utils/slug"
+)
+
+// steps are the Go steps of the versions that have one. They read and write plain
+// rows rather than the models, which keep changing after a migration is written.
+var steps = map[uint64]func(tx *gorm.DB) error{
+	4:  backfillSlugs,
+	10: backfillPostStats,
+}
+
+// backfillSlugs gives a slug to the posts created before slugs existed, unique
+// among them before 0005 enforces it
+func backfillSlugs(tx *gorm.DB) error {
+	type post struct {
+		ID    uint64
+		Title string
+		Slug  string
+	}
+	posts := []post{}
+	err := tx.Table("posts").Select("id, title, slug").Order("id").Scan(&posts).Error
+	if err != nil {
+		return err
+	}
+	taken := map[string]bool{}
+	for _, p := range posts {
+		taken[p.Slug] = p.Slug != ""
+	}
+	for _, p := range posts {
+		if p.Slug != "" {
+			continue
+		}
+		// Titles were stored HTML escaped when slugs did not exist
+		base := slug.Make(html.UnescapeString(p.Title))
+		candidate := base
+		for i := 2; taken[candidate]; i++ {
+			candidate = fmt.Sprintf("%s-%d", base, i)
+		}
+		taken[candidate] = true
+		err = tx.Table("posts").Where("id = ?", p.ID).UpdateColumn("slug", candidate).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillPostStats stores the word count and reading time of the existing posts
+func backfillPostStats(tx *gorm.DB) error {
+	type post struct {
+		ID      uint64
+		Content string
+	}
+	posts := []post{}
+	err := tx.Table("posts").Select("id, content").Order("id").Scan(&posts).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		words := render.WordCount(render.Text(p.Content))
+		err = tx.Table("posts").Where("id = ?", p.ID).UpdateColumns(map[string]interface{}{
+			"word_count":           words,
+			"reading_time_minutes": render.ReadingTime(words),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
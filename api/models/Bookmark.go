@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+type Bookmark struct {
+	ID            uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID        uint32    `gorm:"not null;unique_index:idx_bookmarks_user_post" json:"user_id"`
+	PostID        uint64    `gorm:"not null;unique_index:idx_bookmarks_user_post" json:"post_id"`
+	ReadingListID uint64    `gorm:"not null;default:0;index" json:"reading_list_id"`
+	CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Post is nil and Available false when the post was deleted or is no longer published
+	Post      *Post `gorm:"-" json:"post"`
+	Available bool  `gorm:"-" json:"available"`
+}
+
+func (b *Bookmark) Prepare() {
+	b.ID = 0
+	b.Post = nil
+	b.CreatedAt = time.Now()
+}
+
+func (b *Bookmark) Validate() error {
+	v := apperrors.Validator{}
+	v.Check(b.UserID > 0, "user_id", "Required User")
+	v.Check(b.PostID > 0, "post_id", "Required Post")
+	return v.Err()
+}
+
+func (b *Bookmark) FindBookmark(db *gorm.DB, uid uint32, pid uint64) (*Bookmark, error) {
+	err := db.Model(&Bookmark{}).Where("user_id = ? and post_id = ?", uid, pid).Take(&b).Error
+	if err != nil {
+		return &Bookmark{}, err
+	}
+	return b, nil
+}
+
+func (b *Bookmark) SaveBookmark(db *gorm.DB) (*Bookmark, error) {
+	err := db.Model(&Bookmark{}).Create(&b).Error
+	if err != nil {
+		return &Bookmark{}, err
+	}
+	return b, nil
+}
+
+// MoveBookmark files an existing bookmark under another reading list, 0 for none
+func (b *Bookmark) MoveBookmark(db *gorm.DB, listID uint64) (*Bookmark, error) {
+	err := db.Model(&Bookmark{}).Where("id = ?", b.ID).UpdateColumn("reading_list_id", listID).Error
+	if err != nil {
+		return &Bookmark{}, err
+	}
+	b.ReadingListID = listID
+	return b, nil
+}
+
+func (b *Bookmark) DeleteBookmark(db *gorm.DB, uid uint32, pid uint64) (int64, error) {
+	db = db.Model(&Bookmark{}).Where("user_id = ? and post_id = ?", uid, pid).Delete(&Bookmark{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return 0, apperrors.NewNotFound("Bookmark not found")
+	}
+	return db.RowsAffected, nil
+}
+
+// FindUserBookmarks returns the bookmarks of uid, most recent first, optionally
+// restricted to one reading list. Posts that are gone or unpublished are left
+// out of the bookmark rather than failing the whole listing.
+func (b *Bookmark) FindUserBookmarks(db *gorm.DB, uid uint32, listID *uint64, limit, offset int) (*[]Bookmark, error) {
+	var err error
+	bookmarks := []Bookmark{}
+	query := db.Model(&Bookmark{}).Where("user_id = ?", uid)
+	if listID != nil {
+		query = query.Where("reading_list_id = ?", *listID)
+	}
+	err = query.Order("created_at desc, id desc").Limit(limit).Offset(offset).Find(&bookmarks).Error
+	if err != nil {
+		return &[]Bookmark{}, err
+	}
+	if len(bookmarks) == 0 {
+		return &bookmarks, nil
+	}
+
+	pids := make([]uint64, 0, len(bookmarks))
+	for i := range bookmarks {
+		pids = append(pids, bookmarks[i].PostID)
+	}
+	posts := []Post{}
+	err = db.Model(&Post{}).Where("id in (?)", pids).Find(&posts).Error
+	if err != nil {
+		return &[]Bookmark{}, err
+	}
+	err = loadAuthors(db, posts)
+	if err != nil {
+		return &[]Bookmark{}, err
+	}
+	err = loadLinks(db, posts)
+	if err != nil {
+		return &[]Bookmark{}, err
+	}
+	found := make(map[uint64]*Post, len(posts))
+	for i := range posts {
+		found[posts[i].ID] = &posts[i]
+	}
+	for i := range bookmarks {
+		post, ok := found[bookmarks[i].PostID]
+		if !ok || (!post.IsPublished() && post.AuthorID != uid) {
+			continue
+		}
+		bookmarks[i].Post = post
+		bookmarks[i].Available = true
+	}
+	return &bookmarks, nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+type Follow struct {
+	FollowerID  uint32    `gorm:"primary_key;auto_increment:false" json:"follower_id"`
+	FollowingID uint32    `gorm:"primary_key;auto_increment:false" json:"following_id"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (f *Follow) Prepare() {
+	f.CreatedAt = time.Now()
+}
+
+func (f *Follow) Validate() error {
+	v := apperrors.Validator{}
+	v.Check(f.FollowerID > 0, "follower_id", "Required Follower")
+	v.Check(f.FollowingID > 0, "following_id", "Required Following")
+	if !v.Has("following_id") {
+		v.Check(f.FollowerID != f.FollowingID, "following_id", "Cannot Follow Yourself")
+	}
+	return v.Err()
+}
+
+func (f *Follow) IsFollowing(db *gorm.DB, followerID, followingID uint32) (bool, error) {
+	var count int
+	err := db.Model(&Follow{}).Where("follower_id = ? and following_id = ?", followerID, followingID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (f *Follow) SaveFollow(db *gorm.DB) (*Follow, error) {
+	var err error
+	// Make sure the followed user exists, the caller gets a record not found error otherwise
+	err = db.Model(&User{}).Where("id = ?", f.FollowingID).Take(&User{}).Error
+	if err != nil {
+		return &Follow{}, err
+	}
+	err = db.Model(&Follow{}).Create(&f).Error
+	if err != nil {
+		return &Follow{}, err
+	}
+	return f, nil
+}
+
+func (f *Follow) DeleteFollow(db *gorm.DB, followerID, followingID uint32) (int64, error) {
+	db = db.Model(&Follow{}).Where("follower_id = ? and following_id = ?", followerID, followingID).Delete(&Follow{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return 0, apperrors.NewNotFound("Not Following")
+	}
+	return db.RowsAffected, nil
+}
+
+// FindFollowers returns the users following uid, most recent first
+func (f *Follow) FindFollowers(db *gorm.DB, uid uint32, limit, offset int) (*[]User, error) {
+	var err error
+	users := []User{}
+	err = db.Model(&User{}).Select("users.*").
+		Joins("JOIN follows ON follows.follower_id = users.id").
+		Where("follows.following_id = ?", uid).
+		Order("follows.created_at desc, users.id desc").
+		Limit(limit).Offset(offset).Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
+// FindFollowing returns the users uid follows, most recent first
+func (f *Follow) FindFollowing(db *gorm.DB, uid uint32, limit, offset int) (*[]User, error) {
+	var err error
+	users := []User{}
+	err = db.Model(&User{}).Select("users.*").
+		Joins("JOIN follows ON follows.following_id = users.id").
+		Where("follows.follower_id = ?", uid).
+		Order("follows.created_at desc, users.id desc").
+		Limit(limit).Offset(offset).Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Mention records that a post mentions a user with @nickname
+type Mention struct {
+	PostID    uint64    `gorm:"primary_key;auto_increment:false" json:"post_id"`
+	UserID    uint32    `gorm:"primary_key;auto_increment:false;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// syncMentions makes the mentions of the post match the nicknames that belong to
+// existing users. Unknown nicknames are ignored. Nobody is notified about a draft
+// they cannot open: the users are notified once the post is published, the newly
+// mentioned ones on every save and all of them when a draft gets published.
+func syncMentions(tx *gorm.DB, pid uint64, authorID uint32, nicknames []string, published, wasPublished bool) error {
+	users := []User{}
+	if len(nicknames) > 0 {
+		lowered := make([]string, 0, len(nicknames))
+		for _, nickname := range nicknames {
+			lowered = append(lowered, strings.ToLower(nickname))
+		}
+		err := tx.Model(&User{}).Where("lower(nickname) in (?)", lowered).Find(&users).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	previous := []Mention{}
+	err := tx.Model(&Mention{}).Where("post_id = ?", pid).Find(&previous).Error
+	if err != nil {
+		return err
+	}
+	mentioned := make(map[uint32]bool, len(previous))
+	for _, m := range previous {
+		mentioned[m.UserID] = true
+	}
+
+	err = tx.Where("post_id = ?", pid).Delete(&Mention{}).Error
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		err = tx.Create(&Mention{PostID: pid, UserID: user.ID, CreatedAt: time.Now()}).Error
+		if err != nil {
+			return err
+		}
+		if !published || (mentioned[user.ID] && wasPublished) || user.ID == authorID {
+			continue
+		}
+		notification := Notification{
+			UserID:    user.ID,
+			ActorID:   authorID,
+			PostID:    pid,
+			Kind:      NotificationMention,
+			CreatedAt: time.Now(),
+		}
+		err = tx.Create(&notification).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+const NotificationMention = "mention"
+
+type Notification struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	ActorID   uint32     `gorm:"not null" json:"actor_id"`
+	PostID    uint64     `gorm:"not null" json:"post_id"`
+	Kind      string     `gorm:"size:20;not null" json:"kind"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (n *Notification) FindUserNotifications(db *gorm.DB, uid uint32, unreadOnly bool, limit, offset int) (*[]Notification, error) {
+	notifications := []Notification{}
+	query := db.Model(&Notification{}).Where("user_id = ?", uid)
+	if unreadOnly {
+		query = query.Where("read_at is null")
+	}
+	err := query.Order("created_at desc, id desc").Limit(limit).Offset(offset).Find(&notifications).Error
+	if err != nil {
+		return &[]Notification{}, err
+	}
+	return &notifications, nil
+}
+
+func (n *Notification) MarkAsRead(db *gorm.DB, id uint64, uid uint32) (*Notification, error) {
+	err := db.Model(&Notification{}).Where("id = ? and user_id = ?", id, uid).Take(&n).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return &Notification{}, apperrors.NewNotFound("Notification not found")
+		}
+		return &Notification{}, err
+	}
+	if n.ReadAt != nil {
+		return n, nil
+	}
+	now := time.Now()
+	err = db.Model(&Notification{}).Where("id = ?", n.ID).UpdateColumn("read_at", now).Error
+	if err != nil {
+		return &Notification{}, err
+	}
+	n.ReadAt = &now
+	return n, nil
+}
+
+// pruneBatchSize is how many notifications PruneRead deletes per transaction
+const pruneBatchSize = 1000
+
+// PruneRead deletes the notifications read before the given time and returns how
+// many were deleted. It deletes them in batches and stops between two once ctx is
+// done; a batch runs in a transaction of ctx, rolled back when ctx ends during it.
+func (n *Notification) PruneRead(ctx context.Context, db *gorm.DB, before time.Time) (int64, error) {
+	var pruned int64
+	for {
+		err := ctx.Err()
+		if err != nil {
+			return pruned, err
+		}
+		found, deleted, err := pruneBatch(ctx, db, before)
+		pruned += deleted
+		if err != nil || found < pruneBatchSize {
+			return pruned, err
+		}
+	}
+}
+
+// pruneBatch deletes the oldest batch of notifications to prune, it returns how
+// many it found and how many it deleted
+func pruneBatch(ctx context.Context, db *gorm.DB, before time.Time) (int, int64, error) {
+	tx := db.BeginTx(ctx, nil)
+	if tx.Error != nil {
+		return 0, 0, tx.Error
+	}
+	ids := []uint64{}
+	err := tx.Model(&Notification{}).Where("read_at is not null and read_at < ?", before).
+		Order("id").Limit(pruneBatchSize).Pluck("id", &ids).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, 0, err
+	}
+	if len(ids) == 0 {
+		return 0, 0, tx.Commit().Error
+	}
+	result := tx.Where("id in (?)", ids).Delete(&Notification{})
+	if result.Error != nil {
+		tx.Rollback()
+		return 0, 0, result.Error
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(ids), result.RowsAffected, nil
+}
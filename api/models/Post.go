@@ -1,54 +1,196 @@
 package models
 
 import (
-	"errors"
-	"html"
+	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+	"github.com/planutim/postgres-copy/api/render"
 )
 
+const (
+	PostStatusDraft     = "draft"
+	PostStatusPublished = "published"
+)
+
+const (
+	// ExcerptLength is the size of the excerpt column and of generated excerpts
+	ExcerptLength = 500
+	// generatedExcerptLength keeps excerpts derived from the content short enough for listings
+	generatedExcerptLength = 200
+)
+
+// MaxContentLength is the longest content, in characters, Validate accepts
+var MaxContentLength = 100000
+
+// LinkPrefix is the prefix of the routes the mentions and hashtags of the rendered
+// content link to, the server sets it to its current API version
+var LinkPrefix = "/api/v1"
+
 type Post struct {
 	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
-	Title     string    `gorm:"size:255;not null;unique" json:"title"`
-	Content   string    `gorm:"size:255;not null;" json:"content"`
+	Title     string    `gorm:"size:255;not null;unique_index:idx_posts_author_title" json:"title"`
+	Slug      string    `gorm:"size:255;not null;default:''" json:"slug"`
+	Content   string    `gorm:"type:text;not null;" json:"content,omitempty"`
+	Excerpt   string    `gorm:"size:500;not null;default:''" json:"excerpt"`
 	Author    User      `json:"author"`
-	AuthorID  uint32    `gorm:"not null" json:"author_id"`
+	AuthorID  uint32    `gorm:"not null;unique_index:idx_posts_author_title" json:"author_id"`
+	Status    string    `gorm:"size:20;not null;default:'published'" json:"status"`
 	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Reaction counters, kept in sync by SaveReaction and DeleteReaction
+	LikeCount  int64 `gorm:"not null;default:0" json:"-"`
+	LoveCount  int64 `gorm:"not null;default:0" json:"-"`
+	LaughCount int64 `gorm:"not null;default:0" json:"-"`
+	WowCount   int64 `gorm:"not null;default:0" json:"-"`
+	SadCount   int64 `gorm:"not null;default:0" json:"-"`
+	AngryCount int64 `gorm:"not null;default:0" json:"-"`
+
+	Reactions   map[string]int64 `gorm:"-" json:"reactions"`
+	MyReactions []string         `gorm:"-" json:"my_reactions,omitempty"`
+
+	// Filled from the parsed @mentions and #hashtags when the post is loaded
+	Tags     []string `gorm:"-" json:"tags"`
+	Mentions []string `gorm:"-" json:"mentions"`
+
+	// Content is stored as Markdown, these are the renderings handed out next to it
+	ContentHTML string `gorm:"-" json:"content_html,omitempty"`
+	ContentText string `gorm:"-" json:"content_text,omitempty"`
+
+	// Derived from the content when the post is saved
+	WordCount          int `gorm:"not null;default:0" json:"word_count"`
+	ReadingTimeMinutes int `gorm:"not null;default:0" json:"reading_time_minutes"`
 }
 
 func (p *Post) Prepare() {
 	p.ID = 0
-	p.Title = html.EscapeString(strings.TrimSpace(p.Title))
-	p.Content = html.EscapeString(strings.TrimSpace(p.Content))
+	// Titles are stored as written like the content, every output escapes them
+	p.Title = strings.TrimSpace(p.Title)
+	// The slug is derived from the title when the post is saved
+	p.Slug = ""
+	// Content is raw Markdown, it is sanitized when rendered and never escaped in storage
+	p.Content = strings.TrimSpace(p.Content)
+	p.Excerpt = strings.TrimSpace(p.Excerpt)
+	if p.Excerpt == "" {
+		p.Excerpt = render.Truncate(render.Text(p.Content), generatedExcerptLength)
+	}
+	p.Status = strings.ToLower(strings.TrimSpace(p.Status))
+	if p.Status == "" {
+		p.Status = PostStatusPublished
+	}
 	p.Author = User{}
+	p.Reactions = p.reactionCounts()
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
 }
 
 func (p *Post) Validate() error {
-	if p.Title == "" {
-		return errors.New("Required Title")
-	}
+	v := apperrors.Validator{}
+	v.Check(p.Title != "", "title", "Required Title")
 	if p.Content == "" {
-		return errors.New("Required Content")
+		v.Add("content", "Required Content")
+	} else if utf8.RuneCountInString(p.Content) > MaxContentLength {
+		v.Add("content", "Content Too Long")
 	}
-	if p.AuthorID < 1 {
-		return errors.New("Required Author")
+	v.Check(utf8.RuneCountInString(p.Excerpt) <= ExcerptLength, "excerpt", "Excerpt Too Long")
+	v.Check(p.AuthorID > 0, "author_id", "Required Author")
+	v.Check(p.Status == PostStatusDraft || p.Status == PostStatusPublished, "status", "Invalid Status")
+	return v.Err()
+}
+
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatText     = "text"
+)
+
+// Format keeps only the requested rendering of the content, an empty format keeps
+// both the Markdown source and the HTML
+func (p *Post) Format(format string) error {
+	switch format {
+	case "":
+	case FormatMarkdown:
+		p.ContentHTML = ""
+	case FormatHTML:
+		p.Content = ""
+	case FormatText:
+		p.ContentText = render.Text(p.Content)
+		p.Content = ""
+		p.ContentHTML = ""
+	default:
+		return apperrors.NewValidation("Invalid Format")
 	}
 	return nil
 }
 
+func (p *Post) IsPublished() bool {
+	return p.Status == PostStatusPublished
+}
+
+func (p *Post) AfterFind() error {
+	p.Reactions = p.reactionCounts()
+	return nil
+}
+
+// BeforeSave stores the stats of the content with the post, rendering it on every
+// load would cost a Markdown rendering per row
+func (p *Post) BeforeSave() error {
+	p.computeStats()
+	return nil
+}
+
+// computeStats derives the word count and reading time from the content
+func (p *Post) computeStats() {
+	p.WordCount = render.WordCount(render.Text(p.Content))
+	p.ReadingTimeMinutes = render.ReadingTime(p.WordCount)
+}
+
+// BeforeCreate gives every new post a unique slug, whichever way it is created
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	if p.Slug != "" {
+		return nil
+	}
+	s, err := uniqueSlug(tx, p.Title, 0)
+	if err != nil {
+		return err
+	}
+	p.Slug = s
+	return nil
+}
+
+func (p *Post) reactionCounts() map[string]int64 {
+	return map[string]int64{
+		ReactionLike:  p.LikeCount,
+		ReactionLove:  p.LoveCount,
+		ReactionLaugh: p.LaughCount,
+		ReactionWow:   p.WowCount,
+		ReactionSad:   p.SadCount,
+		ReactionAngry: p.AngryCount,
+	}
+}
+
 func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Create(&p).Error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Post{}).Create(&p).Error
+		if err != nil {
+			return err
+		}
+		return p.syncLinks(tx, false)
+	})
 	if err != nil {
 		return &Post{}, err
 	}
 	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		err = db.Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		if err != nil {
+			return &Post{}, err
+		}
+		err = p.loadLinks(db)
 		if err != nil {
 			return &Post{}, err
 		}
@@ -56,32 +198,250 @@ func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 	return p, nil
 }
 
+// syncLinks stores the tags and mentions parsed from the content of the post.
+// wasPublished tells whether the post was published before this save.
+func (p *Post) syncLinks(tx *gorm.DB, wasPublished bool) error {
+	err := syncPostTags(tx, p.ID, render.Hashtags(p.Content))
+	if err != nil {
+		return err
+	}
+	return syncMentions(tx, p.ID, p.AuthorID, render.Mentions(p.Content), p.IsPublished(), wasPublished)
+}
+
+func (p *Post) loadLinks(db *gorm.DB) error {
+	posts := []Post{*p}
+	err := loadLinks(db, posts)
+	if err != nil {
+		return err
+	}
+	*p = posts[0]
+	return nil
+}
+
+// loadLinks fills in the tags and mentions of the posts and renders their content to HTML with links
+func loadLinks(db *gorm.DB, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	pids := make([]uint64, 0, len(posts))
+	for i := range posts {
+		pids = append(pids, posts[i].ID)
+	}
+
+	type postTagName struct {
+		PostID uint64
+		Name   string
+	}
+	tagNames := []postTagName{}
+	err := db.Table("post_tags").Select("post_tags.post_id, tags.name").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("post_tags.post_id in (?)", pids).Order("tags.name").Scan(&tagNames).Error
+	if err != nil {
+		return err
+	}
+	type postMention struct {
+		PostID   uint64
+		UserID   uint32
+		Nickname string
+	}
+	mentions := []postMention{}
+	err = db.Table("mentions").Select("mentions.post_id, users.id as user_id, users.nickname").
+		Joins("JOIN users ON users.id = mentions.user_id").
+		Where("mentions.post_id in (?)", pids).Order("users.nickname").Scan(&mentions).Error
+	if err != nil {
+		return err
+	}
+
+	tags := make(map[uint64][]string)
+	for _, t := range tagNames {
+		tags[t.PostID] = append(tags[t.PostID], t.Name)
+	}
+	nicknames := make(map[uint64][]string)
+	users := make(map[uint64]map[string]uint32)
+	for _, m := range mentions {
+		nicknames[m.PostID] = append(nicknames[m.PostID], m.Nickname)
+		if users[m.PostID] == nil {
+			users[m.PostID] = make(map[string]uint32)
+		}
+		users[m.PostID][strings.ToLower(m.Nickname)] = m.UserID
+	}
+	for i := range posts {
+		posts[i].Tags = tags[posts[i].ID]
+		if posts[i].Tags == nil {
+			posts[i].Tags = []string{}
+		}
+		posts[i].Mentions = nicknames[posts[i].ID]
+		if posts[i].Mentions == nil {
+			posts[i].Mentions = []string{}
+		}
+		posts[i].ContentHTML = render.HTML(posts[i].Content, users[posts[i].ID], LinkPrefix)
+	}
+	return nil
+}
+
 func (p *Post) FindAllPosts(db *gorm.DB) (*[]Post, error) {
 	var err error
 	posts := []Post{}
-	err = db.Debug().Model(&User{}).Limit(100).Find(&posts).Error
+	err = db.Model(&User{}).Where("status = ?", PostStatusPublished).Limit(100).Find(&posts).Error
 	if err != nil {
 		return &[]Post{}, err
 	}
-	if len(posts) > 0 {
-		for i, _ := range posts {
-			err := db.Debug().Model(&User{}).Where("id = ?", posts[i].AuthorID).Take(&posts[i].Author).Error
-			if err != nil {
-				return &[]Post{}, err
-			}
-		}
+	err = loadAuthors(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadLinks(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+// FindFeed returns published posts by the authors uid follows, newest first.
+// A non zero beforeID restricts the result to posts older than (before, beforeID).
+func (p *Post) FindFeed(db *gorm.DB, uid uint32, before time.Time, beforeID uint64, limit int) (*[]Post, error) {
+	var err error
+	posts := []Post{}
+	query := db.Model(&Post{}).Select("posts.*").
+		Joins("JOIN follows ON follows.following_id = posts.author_id").
+		Where("follows.follower_id = ? and posts.status = ?", uid, PostStatusPublished)
+	if beforeID != 0 {
+		query = query.Where("posts.created_at < ? or (posts.created_at = ? and posts.id < ?)", before, before, beforeID)
+	}
+	err = query.Order("posts.created_at desc, posts.id desc").Limit(limit).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadAuthors(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadLinks(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+// FindPostsByTag returns the published posts tagged with name, newest first
+func (p *Post) FindPostsByTag(db *gorm.DB, name string, limit, offset int) (*[]Post, error) {
+	var err error
+	posts := []Post{}
+	err = db.Model(&Post{}).Select("posts.*").
+		Joins("JOIN post_tags ON post_tags.post_id = posts.id").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("tags.name = ? and posts.status = ?", strings.ToLower(name), PostStatusPublished).
+		Order("posts.created_at desc, posts.id desc").
+		Limit(limit).Offset(offset).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadAuthors(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadLinks(db, posts)
+	if err != nil {
+		return &[]Post{}, err
 	}
 	return &posts, nil
 }
 
+// FindRecentPosts returns the latest published posts, optionally only those of
+// an author or carrying a tag, with their authors and links loaded
+func (p *Post) FindRecentPosts(db *gorm.DB, authorID uint32, tag string, limit int) (*[]Post, error) {
+	var err error
+	posts := []Post{}
+	query := db.Model(&Post{}).Select("posts.*").Where("posts.status = ?", PostStatusPublished)
+	if authorID != 0 {
+		query = query.Where("posts.author_id = ?", authorID)
+	}
+	if tag != "" {
+		query = query.Joins("JOIN post_tags ON post_tags.post_id = posts.id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.name = ?", strings.ToLower(tag))
+	}
+	err = query.Order("posts.created_at desc, posts.id desc").Limit(limit).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadAuthors(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadLinks(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+// FindPostsByAuthors returns the newest published posts of the authors, at most
+// limit per author, newest first, with their tags and mentions but not their
+// authors. Every author gets a query of its own capped by the limit, in a single
+// statement; window functions would do but MySQL 5.7 has none.
+func (p *Post) FindPostsByAuthors(db *gorm.DB, authorIDs []uint32, limit int) (*[]Post, error) {
+	posts := []Post{}
+	if len(authorIDs) == 0 {
+		return &posts, nil
+	}
+	selects := make([]string, 0, len(authorIDs))
+	args := make([]interface{}, 0, 3*len(authorIDs))
+	for i, id := range authorIDs {
+		selects = append(selects, fmt.Sprintf("SELECT * FROM (SELECT * FROM posts WHERE author_id = ? AND status = ? "+
+			"ORDER BY created_at desc, id desc LIMIT ?) author_%d", i))
+		args = append(args, id, PostStatusPublished, limit)
+	}
+	err := db.Raw(strings.Join(selects, " UNION ALL ")+" ORDER BY created_at desc, id desc", args...).Scan(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	err = loadLinks(db, posts)
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+// loadAuthors fills in the Author of every post with a single query
+func loadAuthors(db *gorm.DB, posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint32, 0, len(posts))
+	for i := range posts {
+		ids = append(ids, posts[i].AuthorID)
+	}
+	users := []User{}
+	err := db.Model(&User{}).Where("id in (?)", ids).Find(&users).Error
+	if err != nil {
+		return err
+	}
+	authors := make(map[uint32]User, len(users))
+	for _, u := range users {
+		authors[u.ID] = u
+	}
+	for i := range posts {
+		posts[i].Author = authors[posts[i].AuthorID]
+	}
+	return nil
+}
+
 func (p *Post) FindPostByID(db *gorm.DB, pid uint64) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id = ?", pid).Take(&p).Error
+	err = db.Model(&Post{}).Where("id = ?", pid).Take(&p).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &Post{}, apperrors.NewNotFound("Post not found")
+	}
 	if err != nil {
 		return &Post{}, err
 	}
 	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		err = db.Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		if err != nil {
+			return &Post{}, err
+		}
+		err = p.loadLinks(db)
 		if err != nil {
 			return &Post{}, err
 		}
@@ -91,12 +451,48 @@ func (p *Post) FindPostByID(db *gorm.DB, pid uint64) (*Post, error) {
 
 func (p *Post) UpdateAPost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id = ?", p.ID).Updates(Post{Title: p.Title, Content: p.Content, UpdatedAt: time.Now()}).Error
+	err = db.Transaction(func(tx *gorm.DB) error {
+		statuses := []string{}
+		err := tx.Model(&Post{}).Where("id = ?", p.ID).Pluck("status", &statuses).Error
+		if err != nil {
+			return err
+		}
+		wasPublished := len(statuses) == 1 && statuses[0] == PostStatusPublished
+		err = p.updateSlug(tx)
+		if err != nil {
+			return err
+		}
+		// Updates does not run the hooks of p, the stats are computed here
+		p.computeStats()
+		err = tx.Model(&Post{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+			"title":                p.Title,
+			"slug":                 p.Slug,
+			"content":              p.Content,
+			"excerpt":              p.Excerpt,
+			"status":               p.Status,
+			"word_count":           p.WordCount,
+			"reading_time_minutes": p.ReadingTimeMinutes,
+			"updated_at":           time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return p.syncLinks(tx, wasPublished)
+	})
 	if err != nil {
 		return &Post{}, err
 	}
 	if p.ID != 0 {
-		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		// The reaction counters and the creation time are only in the stored row
+		err = db.Model(&Post{}).Where("id = ?", p.ID).Take(p).Error
+		if err != nil {
+			return &Post{}, err
+		}
+		err = db.Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		if err != nil {
+			return &Post{}, err
+		}
+		err = p.loadLinks(db)
 		if err != nil {
 			return &Post{}, err
 		}
@@ -104,11 +500,73 @@ func (p *Post) UpdateAPost(db *gorm.DB) (*Post, error) {
 	return p, nil
 }
 
+// updateSlug keeps the slug of the post unless its title changed, in which case
+// the old slug goes to the history so that it can be redirected
+func (p *Post) updateSlug(tx *gorm.DB) error {
+	current := Post{}
+	err := tx.Model(&Post{}).Where("id = ?", p.ID).Take(&current).Error
+	if err != nil {
+		return err
+	}
+	p.Slug = current.Slug
+	if current.Title == p.Title && current.Slug != "" {
+		return nil
+	}
+	p.Slug, err = uniqueSlug(tx, p.Title, p.ID)
+	if err != nil || p.Slug == current.Slug {
+		return err
+	}
+	// The post may be getting back one of its previous slugs
+	err = tx.Where("slug = ?", p.Slug).Delete(&PostSlug{}).Error
+	if err != nil {
+		return err
+	}
+	if current.Slug == "" {
+		return nil
+	}
+	return tx.Create(&PostSlug{Slug: current.Slug, PostID: p.ID, CreatedAt: time.Now()}).Error
+}
+
+// FindPostBySlug returns the post currently using the slug
+func (p *Post) FindPostBySlug(db *gorm.DB, s string) (*Post, error) {
+	var err error
+	err = db.Model(&Post{}).Where("slug = ?", s).Take(&p).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	if p.ID != 0 {
+		err = db.Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		if err != nil {
+			return &Post{}, err
+		}
+		err = p.loadLinks(db)
+		if err != nil {
+			return &Post{}, err
+		}
+	}
+	return p, nil
+}
+
+// FindCurrentSlug returns the slug now used by the post that used to be reachable under s
+func (p *Post) FindCurrentSlug(db *gorm.DB, s string) (string, error) {
+	old := PostSlug{}
+	err := db.Model(&PostSlug{}).Where("slug = ?", s).Take(&old).Error
+	if err != nil {
+		return "", err
+	}
+	current := Post{}
+	err = db.Model(&Post{}).Where("id = ?", old.PostID).Take(&current).Error
+	if err != nil {
+		return "", err
+	}
+	return current.Slug, nil
+}
+
 func (p *Post) DeleteAPost(db *gorm.DB, pid uint64, uid uint32) (int64, error) {
-	db = db.Debug().Model(&Post{}).Where("id = ? and author_id = ?", pid, uid).Take(&Post{}).Delete(&Post{})
+	db = db.Model(&Post{}).Where("id = ? and author_id = ?", pid, uid).Take(&Post{}).Delete(&Post{})
 	if db.Error != nil {
 		if gorm.IsRecordNotFoundError(db.Error) {
-			return 0, errors.New("Post not found")
+			return 0, apperrors.NewNotFound("Post not found")
 		}
 		return 0, db.Error
 	}
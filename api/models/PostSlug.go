@@ -0,0 +1,42 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/utils/slug"
+)
+
+// PostSlug keeps the slugs a post had before its title changed, so that old urls keep working
+type PostSlug struct {
+	Slug      string    `gorm:"primary_key;size:255" json:"slug"`
+	PostID    uint64    `gorm:"not null;index" json:"post_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// uniqueSlug derives a slug from the title that no other post uses now or used before
+func uniqueSlug(db *gorm.DB, title string, pid uint64) (string, error) {
+	base := slug.Make(title)
+	candidate := base
+	for i := 2; ; i++ {
+		taken, err := slugTaken(db, candidate, pid)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+func slugTaken(db *gorm.DB, candidate string, pid uint64) (bool, error) {
+	var count int
+	err := db.Model(&Post{}).Where("slug = ? and id <> ?", candidate, pid).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	err = db.Model(&PostSlug{}).Where("slug = ? and post_id <> ?", candidate, pid).Count(&count).Error
+	return count > 0, err
+}
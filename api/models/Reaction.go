@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionLaugh = "laugh"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+	ReactionAngry = "angry"
+)
+
+// ReactionTypes is the fixed set of reactions a user can leave on a post
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionLaugh, ReactionWow, ReactionSad, ReactionAngry}
+
+type Reaction struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PostID    uint64    `gorm:"not null;unique_index:idx_reactions_post_user_type" json:"post_id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_reactions_post_user_type" json:"user_id"`
+	Type      string    `gorm:"size:20;not null;unique_index:idx_reactions_post_user_type" json:"type"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func IsReactionType(t string) bool {
+	for _, rt := range ReactionTypes {
+		if rt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// reactionCounter is the denormalised counter column on posts for a reaction type
+func reactionCounter(t string) string {
+	return t + "_count"
+}
+
+func (r *Reaction) Prepare() {
+	r.ID = 0
+	r.CreatedAt = time.Now()
+}
+
+func (r *Reaction) Validate() error {
+	v := apperrors.Validator{}
+	v.Check(r.PostID > 0, "post_id", "Required Post")
+	v.Check(r.UserID > 0, "user_id", "Required User")
+	if r.Type == "" {
+		v.Add("type", "Required Type")
+	} else if !IsReactionType(r.Type) {
+		v.Add("type", "Invalid Reaction Type")
+	}
+	return v.Err()
+}
+
+func (r *Reaction) HasReacted(db *gorm.DB) (bool, error) {
+	var count int
+	err := db.Model(&Reaction{}).Where("post_id = ? and user_id = ? and type = ?", r.PostID, r.UserID, r.Type).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// SaveReaction stores the reaction and bumps the post counter in the same transaction
+func (r *Reaction) SaveReaction(db *gorm.DB) (*Reaction, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Reaction{}).Create(r).Error
+		if err != nil {
+			return err
+		}
+		counter := reactionCounter(r.Type)
+		return tx.Model(&Post{}).Where("id = ?", r.PostID).UpdateColumn(counter, gorm.Expr(counter+" + ?", 1)).Error
+	})
+	if err != nil {
+		return &Reaction{}, err
+	}
+	return r, nil
+}
+
+// DeleteReaction removes the reaction and decrements the post counter in the same transaction
+func (r *Reaction) DeleteReaction(db *gorm.DB, pid uint64, uid uint32, reactionType string) (int64, error) {
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Reaction{}).Where("post_id = ? and user_id = ? and type = ?", pid, uid, reactionType).Delete(&Reaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if deleted == 0 {
+			return apperrors.NewNotFound("Reaction not found")
+		}
+		counter := reactionCounter(reactionType)
+		return tx.Model(&Post{}).Where("id = ? and "+counter+" > 0", pid).UpdateColumn(counter, gorm.Expr(counter+" - ?", deleted)).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// FindUserReactions returns the reaction types uid left on each of the given posts
+func (r *Reaction) FindUserReactions(db *gorm.DB, uid uint32, pids []uint64) (map[uint64][]string, error) {
+	mine := make(map[uint64][]string)
+	if len(pids) == 0 {
+		return mine, nil
+	}
+	reactions := []Reaction{}
+	err := db.Model(&Reaction{}).Where("user_id = ? and post_id in (?)", uid, pids).Order("id").Find(&reactions).Error
+	if err != nil {
+		return mine, err
+	}
+	for _, reaction := range reactions {
+		mine[reaction.PostID] = append(mine[reaction.PostID], reaction.Type)
+	}
+	return mine, nil
+}
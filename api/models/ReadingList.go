@@ -0,0 +1,85 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+type ReadingList struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_reading_lists_user_name" json:"user_id"`
+	Name      string    `gorm:"size:100;not null;unique_index:idx_reading_lists_user_name" json:"name"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+func (l *ReadingList) Prepare() {
+	l.ID = 0
+	l.Name = html.EscapeString(strings.TrimSpace(l.Name))
+	l.CreatedAt = time.Now()
+	l.UpdatedAt = time.Now()
+}
+
+func (l *ReadingList) Validate() error {
+	v := apperrors.Validator{}
+	if l.Name == "" {
+		v.Add("name", "Required Name")
+	} else if len(l.Name) > 100 {
+		v.Add("name", "Name Too Long")
+	}
+	v.Check(l.UserID > 0, "user_id", "Required User")
+	return v.Err()
+}
+
+func (l *ReadingList) SaveReadingList(db *gorm.DB) (*ReadingList, error) {
+	err := db.Model(&ReadingList{}).Create(&l).Error
+	if err != nil {
+		return &ReadingList{}, err
+	}
+	return l, nil
+}
+
+func (l *ReadingList) FindReadingLists(db *gorm.DB, uid uint32) (*[]ReadingList, error) {
+	lists := []ReadingList{}
+	err := db.Model(&ReadingList{}).Where("user_id = ?", uid).Order("name").Find(&lists).Error
+	if err != nil {
+		return &[]ReadingList{}, err
+	}
+	return &lists, nil
+}
+
+// FindUserReadingList returns the reading list only when it belongs to uid
+func (l *ReadingList) FindUserReadingList(db *gorm.DB, id uint64, uid uint32) (*ReadingList, error) {
+	err := db.Model(&ReadingList{}).Where("id = ? and user_id = ?", id, uid).Take(&l).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return &ReadingList{}, apperrors.NewNotFound("Reading list not found")
+		}
+		return &ReadingList{}, err
+	}
+	return l, nil
+}
+
+// DeleteAReadingList removes the list, its bookmarks are kept and moved out of it
+func (l *ReadingList) DeleteAReadingList(db *gorm.DB, id uint64, uid uint32) (int64, error) {
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ReadingList{}).Where("id = ? and user_id = ?", id, uid).Delete(&ReadingList{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if deleted == 0 {
+			return apperrors.NewNotFound("Reading list not found")
+		}
+		return tx.Model(&Bookmark{}).Where("reading_list_id = ? and user_id = ?", id, uid).UpdateColumn("reading_list_id", 0).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SitemapEntry is one url of the sitemap, Path is relative to the site root
+type SitemapEntry struct {
+	Path    string
+	LastMod time.Time
+}
+
+// sitemapSource describes a table whose rows are listed in the sitemap
+type sitemapSource struct {
+	columns string
+	query   func(db *gorm.DB) *gorm.DB
+	scan    func(rows *sql.Rows) (SitemapEntry, error)
+}
+
+var sitemapPosts = sitemapSource{
+	columns: "id, slug, updated_at",
+	query: func(db *gorm.DB) *gorm.DB {
+		return db.Table("posts").Where("status = ?", PostStatusPublished)
+	},
+	scan: func(rows *sql.Rows) (SitemapEntry, error) {
+		var id uint64
+		var s string
+		var updatedAt time.Time
+		err := rows.Scan(&id, &s, &updatedAt)
+		return SitemapEntry{Path: "/posts/by-slug/" + s, LastMod: updatedAt}, err
+	},
+}
+
+var sitemapUsers = sitemapSource{
+	columns: "id, updated_at",
+	query: func(db *gorm.DB) *gorm.DB {
+		// every profile is public, GET /users/{id} needs no token
+		return db.Table("users")
+	},
+	scan: func(rows *sql.Rows) (SitemapEntry, error) {
+		var id uint32
+		var updatedAt time.Time
+		err := rows.Scan(&id, &updatedAt)
+		return SitemapEntry{Path: fmt.Sprintf("/users/%d", id), LastMod: updatedAt}, err
+	},
+}
+
+func (s sitemapSource) count(db *gorm.DB) (int, error) {
+	var count int
+	err := s.query(db).Count(&count).Error
+	return count, err
+}
+
+// each streams the rows of one sitemap file, in id order, without loading them all
+func (s sitemapSource) each(db *gorm.DB, limit, offset int, fn func(SitemapEntry) error) error {
+	rows, err := s.query(db).Select(s.columns).Order("id").Limit(limit).Offset(offset).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry, err := s.scan(rows)
+		if err != nil {
+			return err
+		}
+		err = fn(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// lastMod is the most recent update among the rows of one sitemap file. The ids
+// bounding the file are looked up first so that only the update of a single row
+// is read back, which keeps the time scannable on every dialect
+func (s sitemapSource) lastMod(db *gorm.DB, limit, offset int) (time.Time, error) {
+	var first, last []uint64
+	err := s.query(db).Order("id").Limit(1).Offset(offset).Pluck("id", &first).Error
+	if err != nil || len(first) == 0 {
+		return time.Time{}, err
+	}
+	err = s.query(db).Order("id").Limit(1).Offset(offset+limit-1).Pluck("id", &last).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+	query := s.query(db).Where("id >= ?", first[0])
+	if len(last) > 0 {
+		query = query.Where("id <= ?", last[0])
+	}
+	var updated []time.Time
+	err = query.Order("updated_at desc").Limit(1).Pluck("updated_at", &updated).Error
+	if err != nil || len(updated) == 0 {
+		return time.Time{}, err
+	}
+	return updated[0], nil
+}
+
+func CountSitemapPosts(db *gorm.DB) (int, error) {
+	return sitemapPosts.count(db)
+}
+
+func EachSitemapPost(db *gorm.DB, limit, offset int, fn func(SitemapEntry) error) error {
+	return sitemapPosts.each(db, limit, offset, fn)
+}
+
+func SitemapPostsLastMod(db *gorm.DB, limit, offset int) (time.Time, error) {
+	return sitemapPosts.lastMod(db, limit, offset)
+}
+
+func CountSitemapUsers(db *gorm.DB) (int, error) {
+	return sitemapUsers.count(db)
+}
+
+func EachSitemapUser(db *gorm.DB, limit, offset int, fn func(SitemapEntry) error) error {
+	return sitemapUsers.each(db, limit, offset, fn)
+}
+
+func SitemapUsersLastMod(db *gorm.DB, limit, offset int) (time.Time, error) {
+	return sitemapUsers.lastMod(db, limit, offset)
+}
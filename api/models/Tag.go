@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type Tag struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	Name      string    `gorm:"size:100;not null;unique" json:"name"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// PostTag links a post to a tag parsed from its content
+type PostTag struct {
+	PostID uint64 `gorm:"primary_key;auto_increment:false" json:"post_id"`
+	TagID  uint64 `gorm:"primary_key;auto_increment:false;index" json:"tag_id"`
+}
+
+func (t *Tag) FindTagByName(db *gorm.DB, name string) (*Tag, error) {
+	err := db.Model(&Tag{}).Where("name = ?", name).Take(&t).Error
+	if err != nil {
+		return &Tag{}, err
+	}
+	return t, nil
+}
+
+// syncPostTags makes the tags of the post match names, creating missing tags on the way
+func syncPostTags(tx *gorm.DB, pid uint64, names []string) error {
+	err := tx.Where("post_id = ?", pid).Delete(&PostTag{}).Error
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		tag := Tag{}
+		err = tx.Where(Tag{Name: name}).Attrs(Tag{CreatedAt: time.Now()}).FirstOrCreate(&tag).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&PostTag{PostID: pid, TagID: tag.ID}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,9 +1,7 @@
 package models
 
 import (
-	"errors"
 	"html"
-	"log"
 	"strings"
 	"time"
 
@@ -11,6 +9,7 @@ import (
 
 	"github.com/badoux/checkmail"
 	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/apperrors"
 )
 
 type User struct {
@@ -46,53 +45,24 @@ func (u *User) Prepare() {
 	u.CreatedAt = time.Now()
 }
 
+// Validate reports every invalid field at once. Logging in needs no nickname.
 func (u *User) Validate(action string) error {
-	switch strings.ToLower(action) {
-	case "update":
-		if u.Nickname == "" {
-			return errors.New("Required Nickname")
-		}
-		if u.Password == "" {
-			return errors.New("Required Password")
-		}
-		if u.Email == "" {
-			return errors.New("Required Email")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Invalid Email")
-		}
-		return nil
-	case "login":
-		if u.Password == "" {
-			return errors.New("Required Password")
-		}
-		if u.Email == "" {
-			return errors.New("Required Email")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Invalid Email")
-		}
-		return nil
-	default:
-		if u.Nickname == "" {
-			return errors.New("Required Nickname")
-		}
-		if u.Password == "" {
-			return errors.New("Required Password")
-		}
-		if u.Email == "" {
-			return errors.New("Required Email")
-		}
-		if err := checkmail.ValidateFormat(u.Email); err != nil {
-			return errors.New("Invalid Email")
-		}
-		return nil
+	v := apperrors.Validator{}
+	if strings.ToLower(action) != "login" {
+		v.Check(u.Nickname != "", "nickname", "Required Nickname")
 	}
+	v.Check(u.Password != "", "password", "Required Password")
+	if u.Email == "" {
+		v.Add("email", "Required Email")
+	} else if err := checkmail.ValidateFormat(u.Email); err != nil {
+		v.Add("email", "Invalid Email")
+	}
+	return v.Err()
 }
 
 func (u *User) SaveUser(db *gorm.DB) (*User, error) {
 	var err error
-	err = db.Debug().Create(&u).Error
+	err = db.Create(&u).Error
 	if err != nil {
 		return &User{}, err
 	}
@@ -102,32 +72,42 @@ func (u *User) SaveUser(db *gorm.DB) (*User, error) {
 func (u *User) FindAllUsers(db *gorm.DB) (*[]User, error) {
 	var err error
 	users := []User{}
-	err = db.Debug().Model(&User{}).Limit(100).Find(&users).Error
+	err = db.Model(&User{}).Limit(100).Find(&users).Error
 	if err != nil {
 		return &[]User{}, err
 	}
 	return &users, err
 }
 
+// FindUsersByIDs returns the users among ids that exist, in no particular order
+func (u *User) FindUsersByIDs(db *gorm.DB, ids []uint32) (*[]User, error) {
+	users := []User{}
+	err := db.Model(&User{}).Where("id in (?)", ids).Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
 func (u *User) FindUserByID(db *gorm.DB, uid uint32) (*User, error) {
 	var err error
-	err = db.Debug().Model(User{}).Where("id = ?", uid).Take(&u).Error
+	err = db.Model(User{}).Where("id = ?", uid).Take(&u).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return &User{}, apperrors.NewNotFound("User not found")
+	}
 	if err != nil {
 		return &User{}, err
 	}
-	if gorm.IsRecordNotFoundError(err) {
-		return &User{}, errors.New("User Not Found")
-	}
-	return u, err
+	return u, nil
 }
 
 func (u *User) UpdateAUser(db *gorm.DB, uid uint32) (*User, error) {
 	//To hash the password
 	err := u.BeforeSave()
 	if err != nil {
-		log.Fatal(err)
+		return &User{}, err
 	}
-	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).UpdateColumns(
+	db = db.Model(&User{}).Where("id = ?", uid).Take(&User{}).UpdateColumns(
 		map[string]interface{}{
 			"password":   u.Password,
 			"nickname":   u.Nickname,
@@ -135,11 +115,14 @@ func (u *User) UpdateAUser(db *gorm.DB, uid uint32) (*User, error) {
 			"updated_at": time.Now(),
 		},
 	)
+	if gorm.IsRecordNotFoundError(db.Error) {
+		return &User{}, apperrors.NewNotFound("User not found")
+	}
 	if db.Error != nil {
 		return &User{}, db.Error
 	}
 	//This is the display the updated user
-	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Error
+	err = db.Model(&User{}).Where("id = ?", uid).Take(&u).Error
 	if err != nil {
 		return &User{}, err
 	}
@@ -147,7 +130,10 @@ func (u *User) UpdateAUser(db *gorm.DB, uid uint32) (*User, error) {
 }
 
 func (u *User) DeleteAUser(db *gorm.DB, uid uint32) (int64, error) {
-	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).Delete(&User{})
+	db = db.Model(&User{}).Where("id = ?", uid).Take(&User{}).Delete(&User{})
+	if gorm.IsRecordNotFoundError(db.Error) {
+		return 0, apperrors.NewNotFound("User not found")
+	}
 	if db.Error != nil {
 		return 0, db.Error
 	}
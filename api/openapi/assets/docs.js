@@ -0,0 +1,180 @@
+// Renders the OpenAPI document of the API: its operations grouped by tag, with
+// their parameters, request body and responses. Every text of the document is
+// set as text, never as HTML.
+(function () {
+  "use strict";
+
+  var methods = ["get", "put", "post", "delete", "options", "head", "patch", "trace"];
+
+  function element(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) {
+      node.className = className;
+    }
+    if (text !== undefined && text !== null) {
+      node.textContent = String(text);
+    }
+    return node;
+  }
+
+  // resolve follows a local $ref, such as #/components/responses/NotFound
+  function resolve(doc, value) {
+    var seen = 0;
+    while (value && value.$ref && seen < 16) {
+      var node = doc;
+      value.$ref.replace(/^#\//, "").split("/").forEach(function (token) {
+        token = token.replace(/~1/g, "/").replace(/~0/g, "~");
+        node = node ? node[token] : undefined;
+      });
+      value = node;
+      seen++;
+    }
+    return value || {};
+  }
+
+  // schemaName is a short description of a schema: its name, or its type
+  function schemaName(schema) {
+    if (!schema) {
+      return "";
+    }
+    if (schema.$ref) {
+      return schema.$ref.split("/").pop();
+    }
+    if (schema.type === "array") {
+      return schemaName(schema.items) + "[]";
+    }
+    if (schema.enum) {
+      return schema.enum.join(" | ");
+    }
+    return [].concat(schema.type || "object").join(" | ");
+  }
+
+  function content(doc, body) {
+    var list = element("ul", "content");
+    Object.keys(body.content || {}).forEach(function (type) {
+      var item = element("li");
+      item.appendChild(element("code", null, type));
+      var name = schemaName(body.content[type].schema);
+      if (name) {
+        item.appendChild(document.createTextNode(" " + name));
+      }
+      list.appendChild(item);
+    });
+    return list;
+  }
+
+  function parameters(doc, params) {
+    var table = element("table");
+    var head = element("tr");
+    ["Name", "In", "Type", "Description"].forEach(function (title) {
+      head.appendChild(element("th", null, title));
+    });
+    table.appendChild(head);
+    params.forEach(function (param) {
+      param = resolve(doc, param);
+      var row = element("tr");
+      row.appendChild(element("td", param.required ? "required" : null, param.name));
+      row.appendChild(element("td", null, param.in));
+      row.appendChild(element("td", null, schemaName(param.schema)));
+      row.appendChild(element("td", null, param.description || ""));
+      table.appendChild(row);
+    });
+    return table;
+  }
+
+  function operation(doc, path, method, pathItem) {
+    var op = pathItem[method];
+    var section = element("details", "operation " + method);
+    var summary = element("summary");
+    summary.appendChild(element("span", "method", method.toUpperCase()));
+    summary.appendChild(element("code", "path", path));
+    summary.appendChild(element("span", "summary", op.summary || ""));
+    section.appendChild(summary);
+
+    if (op.description) {
+      section.appendChild(element("p", null, op.description));
+    }
+    var params = (pathItem.parameters || []).concat(op.parameters || []);
+    if (params.length > 0) {
+      section.appendChild(element("h4", null, "Parameters"));
+      section.appendChild(parameters(doc, params));
+    }
+    if (op.requestBody) {
+      var body = resolve(doc, op.requestBody);
+      section.appendChild(element("h4", null, "Request body"));
+      if (body.description) {
+        section.appendChild(element("p", null, body.description));
+      }
+      section.appendChild(content(doc, body));
+    }
+    section.appendChild(element("h4", null, "Responses"));
+    var responses = element("dl");
+    Object.keys(op.responses || {}).forEach(function (status) {
+      var response = resolve(doc, op.responses[status]);
+      responses.appendChild(element("dt", null, status));
+      var description = element("dd", null, response.description || "");
+      if (response.content) {
+        description.appendChild(content(doc, response));
+      }
+      responses.appendChild(description);
+    });
+    section.appendChild(responses);
+    return section;
+  }
+
+  function render(doc, root) {
+    var info = doc.info || {};
+    var header = element("header");
+    header.appendChild(element("h1", null, (info.title || "API") + " " + (info.version || "")));
+    if (info.description) {
+      header.appendChild(element("p", null, info.description));
+    }
+    header.appendChild(element("a", null, "openapi.json")).href = "/openapi.json";
+    root.appendChild(header);
+
+    // Operations are grouped by their first tag, in the order of the tags of the document
+    var groups = {};
+    var order = (doc.tags || []).map(function (tag) { return tag.name; });
+    Object.keys(doc.paths || {}).forEach(function (path) {
+      var pathItem = doc.paths[path];
+      methods.forEach(function (method) {
+        if (!pathItem[method]) {
+          return;
+        }
+        var tag = (pathItem[method].tags || ["Other"])[0];
+        if (order.indexOf(tag) < 0) {
+          order.push(tag);
+        }
+        (groups[tag] = groups[tag] || []).push(operation(doc, path, method, pathItem));
+      });
+    });
+    order.forEach(function (tag) {
+      if (!groups[tag]) {
+        return;
+      }
+      var section = element("section");
+      section.appendChild(element("h2", null, tag));
+      groups[tag].forEach(function (node) {
+        section.appendChild(node);
+      });
+      root.appendChild(section);
+    });
+  }
+
+  window.addEventListener("load", function () {
+    var root = document.getElementById("docs");
+    fetch("/openapi.json")
+      .then(function (resp) {
+        if (!resp.ok) {
+          throw new Error(resp.status + " " + resp.statusText);
+        }
+        return resp.json();
+      })
+      .then(function (doc) {
+        render(doc, root);
+      })
+      .catch(function (err) {
+        root.appendChild(element("p", "error", "Cannot load the OpenAPI document: " + err.message));
+      });
+  });
+})();
@@ -0,0 +1,60 @@
+// Package openapi serves the OpenAPI document of the API and a page browsing it.
+// openapi.json is maintained by hand along with the routes, a test checks both
+// the routes and the responses of the handlers against it.
+package openapi
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/planutim/postgres-copy/api/responses"
+)
+
+//go:embed openapi.json
+var document []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+//go:embed assets
+var assets embed.FS
+
+// Document is the OpenAPI 3.1 document of the API
+func Document() []byte {
+	return document
+}
+
+// Handler serves the document
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(document)
+}
+
+// DocsHandler serves a page browsing the document it reads from /openapi.json
+func DocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(docsPage)
+}
+
+// AssetsHandler serves the scripts and styles of the docs page from /docs/assets
+func AssetsHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/docs/assets/")
+	extension := path.Ext(name)
+	if extension != ".js" && extension != ".css" {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Asset not found"))
+		return
+	}
+	content, err := fs.ReadFile(assets, "assets/"+name)
+	if err != nil {
+		responses.ERROR(w, http.StatusNotFound, errors.New("Asset not found"))
+		return
+	}
+	w.Header().Set("Content-Type", mime.TypeByExtension(extension))
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(content)
+}
@@ -0,0 +1,27 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Linkify turns @nickname and #hashtag tokens of already escaped text into links
+// to the routes under prefix, such as /api/v1. users maps lower cased nicknames to
+// user ids, mentions of anybody else stay plain text.
+func Linkify(text string, users map[string]uint32, prefix string) string {
+	text = replaceTokens(mentionPattern, text, func(nickname string) string {
+		uid, ok := users[strings.ToLower(nickname)]
+		if !ok {
+			return "@" + nickname
+		}
+		return fmt.Sprintf(`<a href="%s/users/%d" class="mention">@%s</a>`, prefix, uid, html.EscapeString(nickname))
+	})
+	return replaceTokens(hashtagPattern, text, func(tag string) string {
+		if len(tag) > MaxTagLength {
+			return "#" + tag
+		}
+		return fmt.Sprintf(`<a href="%s/tags/%s/posts" class="hashtag">#%s</a>`, prefix, url.PathEscape(strings.ToLower(tag)), html.EscapeString(tag))
+	})
+}
@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bytes"
+	stdhtml "html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/net/html"
+)
+
+var (
+	markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+	// policy is the allowlist applied to every piece of HTML rendered from user content
+	policy = newPolicy()
+
+	stripAll   = bluemonday.StrictPolicy()
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(mention|hashtag)$`)).OnElements("a")
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// HTML renders markdown source to sanitized HTML. Mentions of the users in the
+// map and hashtags are turned into links under prefix, except inside links and code.
+func HTML(source string, users map[string]uint32, prefix string) string {
+	var rendered bytes.Buffer
+	err := markdown.Convert([]byte(source), &rendered)
+	if err != nil {
+		// goldmark only fails on writer errors, which a buffer never returns
+		return ""
+	}
+	return policy.Sanitize(linkifyHTML(rendered.String(), users, prefix))
+}
+
+// Text renders markdown source to plain text with the markup removed
+func Text(source string) string {
+	var rendered bytes.Buffer
+	err := markdown.Convert([]byte(source), &rendered)
+	if err != nil {
+		return ""
+	}
+	text := stdhtml.UnescapeString(stripAll.Sanitize(rendered.String()))
+	return strings.TrimSpace(whitespace.ReplaceAllString(text, " "))
+}
+
+// linkifyHTML runs Linkify over the text nodes of an HTML fragment that are not
+// already part of a link or a code span
+func linkifyHTML(fragment string, users map[string]uint32, prefix string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	skip := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				return b.String()
+			}
+			// Unparsable output is left for the sanitizer to deal with
+			return fragment
+		}
+		raw := string(z.Raw())
+		switch tt {
+		case html.StartTagToken, html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "a", "code", "pre":
+				if tt == html.StartTagToken {
+					skip++
+				} else if skip > 0 {
+					skip--
+				}
+			}
+			b.WriteString(raw)
+		case html.TextToken:
+			if skip > 0 {
+				b.WriteString(raw)
+			} else {
+				b.WriteString(Linkify(raw, users, prefix))
+			}
+		default:
+			b.WriteString(raw)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package render
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Truncate shortens plain text to at most max runes, cutting at a word boundary
+// and marking the cut with an ellipsis
+func Truncate(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	cut := string(runes[:max-1])
+	if i := strings.LastIndexAny(cut, " \t\n"); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \t\n.,;:") + "…"
+}
+
+// WordCount counts the whitespace separated words of plain text
+func WordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+// WordsPerMinute is the reading speed reading times are estimated with
+const WordsPerMinute = 200
+
+// ReadingTime estimates the minutes it takes to read words, at least one minute for
+// any text
+func ReadingTime(words int) int {
+	minutes := (words + WordsPerMinute - 1) / WordsPerMinute
+	if minutes < 1 && words > 0 {
+		minutes = 1
+	}
+	return minutes
+}
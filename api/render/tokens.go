@@ -0,0 +1,63 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+)
+
+// A mention or hashtag only starts a token when it is not glued to a word, so
+// e-mail addresses and escaped entities such as &#39; are left alone.
+var (
+	mentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_@&/])@([\p{L}\p{N}_]+(?:[.\-][\p{L}\p{N}_]+)*)`)
+	hashtagPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_#&/])#(\p{L}[\p{L}\p{N}_]*)`)
+)
+
+const MaxTagLength = 100
+
+// Mentions returns the distinct nicknames mentioned as @nickname in the text, in order of appearance
+func Mentions(text string) []string {
+	return distinct(mentionPattern.FindAllStringSubmatch(text, -1), false)
+}
+
+// Hashtags returns the distinct lower cased #hashtag names found in the text, in order of appearance
+func Hashtags(text string) []string {
+	tags := []string{}
+	for _, tag := range distinct(hashtagPattern.FindAllStringSubmatch(text, -1), true) {
+		if len(tag) <= MaxTagLength {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func distinct(matches [][]string, lower bool) []string {
+	seen := map[string]bool{}
+	tokens := []string{}
+	for _, m := range matches {
+		token := m[2]
+		key := strings.ToLower(token)
+		if lower {
+			token = key
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// replaceTokens calls repl for every token matched by pattern, keeping the character in front of it
+func replaceTokens(pattern *regexp.Regexp, text string, repl func(token string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		// loc[4]:loc[5] is the token, the sigil sits right before it
+		b.WriteString(text[last : loc[4]-1])
+		b.WriteString(repl(text[loc[4]:loc[5]]))
+		last = loc[5]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,49 @@
+// Package requestid gives every request an ID, sent back in the X-Request-ID header
+// so that users can quote it and it can be found in the logs.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+// Header carries the ID of a request, kept when the client or a proxy sets it
+const Header = "X-Request-ID"
+
+var valid = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type key struct{}
+
+// New is a random 16 bytes hexadecimal ID
+func New() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FromContext is the ID of the request ctx belongs to, empty outside a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// NewContext stores the request ID in ctx
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// Middleware keeps the ID sent in the request headers when it looks safe to log and
+// echo, or makes a new one, and sets it on the response headers
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid.MatchString(id) {
+			id = New()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
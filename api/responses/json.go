@@ -3,9 +3,17 @@ package responses
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
+
+	"github.com/planutim/postgres-copy/api/apperrors"
+	"github.com/planutim/postgres-copy/api/requestid"
 )
 
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// JSON writes data with the given status
 func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.WriteHeader(statusCode)
 	err := json.NewEncoder(w).Encode(data)
@@ -14,14 +22,97 @@ func JSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
+// NoContent writes a 204 response, which cannot have a body
+func NoContent(w http.ResponseWriter) {
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ProblemDetails is the RFC 7807 body of every error response. The ID of the request
+// lets users quote the error in bug reports.
+type ProblemDetails struct {
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Detail    string                 `json:"detail,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Errors    []apperrors.FieldError `json:"errors,omitempty"`
+}
+
+// ProblemType is the URI reference identifying the kind of problem
+func ProblemType(kind apperrors.Kind) string {
+	return "/problems/" + kind.Slug()
+}
+
+// kindOfStatus is the kind of error a status stands for
+func kindOfStatus(statusCode int) apperrors.Kind {
+	switch statusCode {
+	case http.StatusNotFound:
+		return apperrors.NotFound
+	case http.StatusConflict:
+		return apperrors.Conflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return apperrors.Validation
+	case http.StatusUnauthorized:
+		return apperrors.Unauthorized
+	case http.StatusForbidden:
+		return apperrors.Forbidden
+	}
+	return apperrors.Internal
+}
+
+func writeProblem(w http.ResponseWriter, problem ProblemDetails) {
+	problem.Title = http.StatusText(problem.Status)
+	problem.RequestID = w.Header().Get(requestid.Header)
+	w.Header().Set("Content-Type", ProblemContentType)
+	// RFC 7235 asks 401 responses to tell how to authenticate
+	if problem.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	JSON(w, problem.Status, problem)
+}
+
+// ERROR writes err as problem details with the given status. The problem type is
+// the kind of err, or the one the status stands for when err has no kind.
 func ERROR(w http.ResponseWriter, statusCode int, err error) {
-	if err != nil {
-		JSON(w, statusCode, struct {
-			Error string `json:"error"`
-		}{
-			Error: err.Error(),
+	if err == nil {
+		writeProblem(w, ProblemDetails{Type: ProblemType(apperrors.Validation), Status: http.StatusBadRequest})
+		return
+	}
+	kind := apperrors.KindOf(err)
+	if kind == apperrors.Internal {
+		kind = kindOfStatus(statusCode)
+	}
+	typ := ProblemType(kind)
+	if kind == apperrors.Internal && statusCode < 500 {
+		// RFC 7807 types problems that are no more than their status about:blank
+		typ = "about:blank"
+	}
+	writeProblem(w, ProblemDetails{
+		Type:   typ,
+		Status: statusCode,
+		Detail: err.Error(),
+		Errors: apperrors.Fields(err),
+	})
+}
+
+// Problem writes err as problem details with the status of its kind. Errors of no
+// kind are internal: they are logged and their message is not shown.
+func Problem(w http.ResponseWriter, err error) {
+	kind := apperrors.KindOf(err)
+	if kind == apperrors.Internal {
+		slog.Error("internal error", "request_id", w.Header().Get(requestid.Header), "error", err)
+		writeProblem(w, ProblemDetails{
+			Type:   ProblemType(kind),
+			Status: kind.Status(),
+			Detail: "The server could not complete the request",
 		})
 		return
 	}
-	JSON(w, http.StatusBadRequest, nil)
+	writeProblem(w, ProblemDetails{
+		Type:   ProblemType(kind),
+		Status: kind.Status(),
+		Detail: err.Error(),
+		Errors: apperrors.Fields(err),
+	})
 }
@@ -0,0 +1,61 @@
+package seed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture is a dataset to load. Users are identified by their email, posts by their
+// author and title and tags by their name; posts refer to their author by email.
+type Fixture struct {
+	Users []UserFixture `json:"users" yaml:"users"`
+	Tags  []string      `json:"tags" yaml:"tags"`
+	Posts []PostFixture `json:"posts" yaml:"posts"`
+}
+
+type UserFixture struct {
+	Nickname string `json:"nickname" yaml:"nickname"`
+	Email    string `json:"email" yaml:"email"`
+	Password string `json:"password" yaml:"password"`
+}
+
+type PostFixture struct {
+	Author  string `json:"author" yaml:"author"`
+	Title   string `json:"title" yaml:"title"`
+	Content string `json:"content" yaml:"content"`
+	Excerpt string `json:"excerpt,omitempty" yaml:"excerpt,omitempty"`
+	Status  string `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// ReadFixture parses a YAML or JSON fixture file, picked by its extension
+func ReadFixture(path string) (*Fixture, error) {
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fixture := Fixture{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(body, &fixture)
+	case ".json":
+		err = json.Unmarshal(body, &fixture)
+	default:
+		return nil, fmt.Errorf("unknown fixture format %q, use .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &fixture, nil
+}
+
+// Merge appends the records of other to the fixture
+func (f *Fixture) Merge(other *Fixture) {
+	f.Users = append(f.Users, other.Users...)
+	f.Tags = append(f.Tags, other.Tags...)
+	f.Posts = append(f.Posts, other.Posts...)
+}
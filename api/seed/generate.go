@@ -0,0 +1,118 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// GenerateOptions sizes a generated dataset. The same Seed always gives the same data.
+type GenerateOptions struct {
+	Users int
+	Posts int
+	Tags  int
+	Seed  int64
+	// Password of every generated user
+	Password string
+}
+
+var firstNames = []string{"ada", "alan", "grace", "linus", "margaret", "ken", "barbara", "dennis", "frances", "john", "radia", "tim", "hedy", "edsger", "karen", "niklaus", "sophie", "guido", "anita", "bjarne"}
+
+var lastNames = []string{"lovelace", "turing", "hopper", "torvalds", "hamilton", "thompson", "liskov", "ritchie", "allen", "mccarthy", "perlman", "berners", "lamarr", "dijkstra", "jones", "wirth", "wilson", "rossum", "borg", "stroustrup"}
+
+var tagNames = []string{"golang", "postgres", "docker", "kubernetes", "testing", "security", "performance", "design", "api", "frontend", "devops", "career", "opensource", "databases", "cloud", "linux", "tutorial", "architecture", "rust", "python"}
+
+var words = []string{"simple", "fast", "reliable", "scalable", "modern", "practical", "hidden", "missing", "better", "small", "quick", "deep", "honest", "boring", "clean", "lazy", "friendly", "strict", "tiny", "robust"}
+
+var subjects = []string{"services", "queries", "migrations", "deployments", "interfaces", "tests", "caches", "indexes", "handlers", "pipelines", "errors", "logs", "configs", "schemas", "releases", "teams", "reviews", "benchmarks", "containers", "feeds"}
+
+var sentences = []string{
+	"Most of the work happens before the first line of code is written.",
+	"We measured everything twice and still got surprised in production.",
+	"The trick is to keep the happy path obvious and the edge cases explicit.",
+	"Nobody reads the documentation until something breaks at night.",
+	"A small change in the schema saved us hours of debugging later.",
+	"It turns out the slowest part was waiting for the network.",
+	"Naming things well remains the hardest problem we solve every week.",
+	"Start with the simplest version that could possibly work.",
+	"Every abstraction leaks eventually, pick the ones that leak slowly.",
+	"Good defaults matter more than a long list of options.",
+}
+
+// Generate builds a realistic looking dataset of users, tags and posts. Posts use
+// the generated tags as #hashtags and mention other users. There is no comment
+// model in the API, so no comments are generated.
+func Generate(opts GenerateOptions) *Fixture {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	fixture := Fixture{}
+	if opts.Password == "" {
+		opts.Password = "password"
+	}
+
+	for i := 0; i < opts.Users; i++ {
+		first := firstNames[rng.Intn(len(firstNames))]
+		last := lastNames[rng.Intn(len(lastNames))]
+		nickname := fmt.Sprintf("%s.%s%d", first, last, i+1)
+		fixture.Users = append(fixture.Users, UserFixture{
+			Nickname: nickname,
+			Email:    nickname + "@example.com",
+			Password: opts.Password,
+		})
+	}
+
+	for i := 0; i < opts.Tags; i++ {
+		name := tagNames[i%len(tagNames)]
+		if i >= len(tagNames) {
+			name = fmt.Sprintf("%s%d", name, i/len(tagNames)+1)
+		}
+		fixture.Tags = append(fixture.Tags, name)
+	}
+
+	if len(fixture.Users) == 0 {
+		return &fixture
+	}
+	for i := 0; i < opts.Posts; i++ {
+		author := fixture.Users[rng.Intn(len(fixture.Users))]
+		adjective := words[rng.Intn(len(words))]
+		title := fmt.Sprintf("%s %s %s, part %d",
+			strings.ToUpper(adjective[:1])+adjective[1:], words[rng.Intn(len(words))], subjects[rng.Intn(len(subjects))], i+1)
+
+		var content strings.Builder
+		paragraphs := 2 + rng.Intn(4)
+		for p := 0; p < paragraphs; p++ {
+			if p > 0 {
+				content.WriteString("\n\n")
+			}
+			for s := 0; s < 3+rng.Intn(4); s++ {
+				if s > 0 {
+					content.WriteString(" ")
+				}
+				content.WriteString(sentences[rng.Intn(len(sentences))])
+			}
+		}
+		if len(fixture.Tags) > 0 {
+			content.WriteString("\n\n")
+			for t := 0; t < 1+rng.Intn(3); t++ {
+				content.WriteString("#" + fixture.Tags[rng.Intn(len(fixture.Tags))] + " ")
+			}
+		}
+		if len(fixture.Users) > 1 && rng.Intn(3) == 0 {
+			mentioned := fixture.Users[rng.Intn(len(fixture.Users))]
+			if mentioned.Email != author.Email {
+				content.WriteString("\n\nThanks to @" + mentioned.Nickname + " for the review.")
+			}
+		}
+
+		status := ""
+		if rng.Intn(10) == 0 {
+			status = "draft"
+		}
+		fixture.Posts = append(fixture.Posts, PostFixture{
+			Author:  author.Email,
+			Title:   title,
+			Content: strings.TrimSpace(content.String()),
+			Status:  status,
+		})
+	}
+	return &fixture
+}
@@ -1,57 +1,196 @@
 package seed
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
 
 	"github.com/jinzhu/gorm"
 	"github.com/planutim/postgres-copy/api/models"
 )
 
-var users = []models.User{
-	{
-		Nickname: "Steven victor",
-		Email:    "steven@gmail.com",
-		Password: "steven123",
+// Sample is the small dataset loaded when no fixture is given
+var Sample = Fixture{
+	Users: []UserFixture{
+		{
+			Nickname: "Steven victor",
+			Email:    "steven@gmail.com",
+			Password: "steven123",
+		},
+		{
+			Nickname: "Martin Luther",
+			Email:    "lutherm@gmail.com",
+			Password: "password",
+		},
 	},
-	{
-		Nickname: "Martin Luther",
-		Email:    "lutherm@gmail.com",
-		Password: "password",
+	Posts: []PostFixture{
+		{
+			Author:  "steven@gmail.com",
+			Title:   "Title 1",
+			Content: "Hello world 1",
+		},
+		{
+			Author:  "lutherm@gmail.com",
+			Title:   "Title 2",
+			Content: "Hello world 2",
+		},
 	},
 }
 
-var posts = []models.Post{
-	{
-		Title:   "Title 1",
-		Content: "Hello world 1",
-	},
-	{
-		Title:   "Title 2",
-		Content: "Hello world 2",
-	},
+// Result counts what Apply did to every kind of record
+type Result struct {
+	UsersCreated int
+	UsersUpdated int
+	TagsCreated  int
+	PostsCreated int
+	PostsUpdated int
 }
 
-func Load(db *gorm.DB) {
-	err := db.Debug().DropTableIfExists(&models.Post{}, &models.User{}).Error
-	if err != nil {
-		log.Fatalf("cannot drop table: %v", err)
+func (r Result) String() string {
+	return fmt.Sprintf("users: %d created, %d updated; tags: %d created; posts: %d created, %d updated",
+		r.UsersCreated, r.UsersUpdated, r.TagsCreated, r.PostsCreated, r.PostsUpdated)
+}
+
+// Apply loads the fixture. With upsert, records already present under the same
+// natural key are updated instead of failing, so that loading a fixture twice
+// gives the same database as loading it once.
+func Apply(db *gorm.DB, fixture *Fixture, upsert bool) (Result, error) {
+	result := Result{}
+	authors := map[string]uint32{}
+
+	for _, u := range fixture.Users {
+		user := models.User{Nickname: u.Nickname, Email: u.Email, Password: u.Password}
+		user.Prepare()
+		err := user.Validate("")
+		if err != nil {
+			return result, fmt.Errorf("user %s: %v", u.Email, err)
+		}
+		existing := models.User{}
+		err = db.Model(&models.User{}).Where("email = ?", user.Email).Take(&existing).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return result, err
+		}
+		if err == nil && upsert {
+			updated, err := upsertUser(db, &existing, &user)
+			if err != nil {
+				return result, fmt.Errorf("user %s: %v", u.Email, err)
+			}
+			if updated {
+				result.UsersUpdated++
+			}
+			authors[user.Email] = existing.ID
+			continue
+		}
+		_, err = user.SaveUser(db)
+		if err != nil {
+			return result, fmt.Errorf("user %s: %v", u.Email, err)
+		}
+		authors[user.Email] = user.ID
+		result.UsersCreated++
 	}
 
-	err = db.Debug().AutoMigrate(&models.User{}, &models.Post{}).Error
-	if err != nil {
-		log.Fatalf("cannot migrate table: %v", err)
+	for _, name := range fixture.Tags {
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+		if name == "" {
+			continue
+		}
+		tag := models.Tag{}
+		err := db.Where("name = ?", name).Take(&tag).Error
+		if err == nil {
+			if !upsert {
+				return result, fmt.Errorf("tag %s: Tag Already Exists", name)
+			}
+			continue
+		}
+		if !gorm.IsRecordNotFoundError(err) {
+			return result, err
+		}
+		err = db.Create(&models.Tag{Name: name}).Error
+		if err != nil {
+			return result, fmt.Errorf("tag %s: %v", name, err)
+		}
+		result.TagsCreated++
 	}
 
-	for i, _ := range users {
-		err = db.Debug().Model(&models.User{}).Create(&users[i]).Error
+	for _, p := range fixture.Posts {
+		authorID, err := authorOf(db, authors, p.Author)
 		if err != nil {
-			log.Fatalf("cannot seed users table: %v", err)
+			return result, fmt.Errorf("post %q: %v", p.Title, err)
 		}
-		posts[i].AuthorID = users[i].ID
+		post := models.Post{Title: p.Title, Content: p.Content, Excerpt: p.Excerpt, Status: p.Status, AuthorID: authorID}
+		post.Prepare()
+		err = post.Validate()
+		if err != nil {
+			return result, fmt.Errorf("post %q: %v", p.Title, err)
+		}
+		existing := models.Post{}
+		err = db.Model(&models.Post{}).Where("author_id = ? and title = ?", post.AuthorID, post.Title).Take(&existing).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return result, err
+		}
+		if err == nil && upsert {
+			if existing.Content == post.Content && existing.Excerpt == post.Excerpt && existing.Status == post.Status {
+				continue
+			}
+			post.ID = existing.ID
+			_, err = post.UpdateAPost(db)
+			if err != nil {
+				return result, fmt.Errorf("post %q: %v", p.Title, err)
+			}
+			result.PostsUpdated++
+			continue
+		}
+		_, err = post.SavePost(db)
+		if err != nil {
+			return result, fmt.Errorf("post %q: %v", p.Title, err)
+		}
+		result.PostsCreated++
+	}
+	return result, nil
+}
 
-		err = db.Debug().Model(&models.Post{}).Create(&posts[i]).Error
+// upsertUser brings an existing user in line with the fixture, the password is
+// only hashed again when it does not match already
+func upsertUser(db *gorm.DB, existing, user *models.User) (bool, error) {
+	columns := map[string]interface{}{}
+	if existing.Nickname != user.Nickname {
+		columns["nickname"] = user.Nickname
+	}
+	if models.VerifyPassword(existing.Password, user.Password) != nil {
+		hashedPassword, err := models.Hash(user.Password)
 		if err != nil {
-			log.Fatalf("cannot seed posts table: %v", err)
+			return false, err
 		}
+		columns["password"] = string(hashedPassword)
 	}
+	if len(columns) == 0 {
+		return false, nil
+	}
+	err := db.Model(&models.User{}).Where("id = ?", existing.ID).UpdateColumns(columns).Error
+	return err == nil, err
+}
+
+// authorOf resolves the email a post fixture names as its author, looking in the
+// database for users the fixture itself does not define
+func authorOf(db *gorm.DB, authors map[string]uint32, email string) (uint32, error) {
+	email = html.EscapeString(strings.TrimSpace(email))
+	if id, ok := authors[email]; ok {
+		return id, nil
+	}
+	user := models.User{}
+	err := db.Model(&models.User{}).Where("email = ?", email).Take(&user).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, errors.New("Author not found: " + email)
+	}
+	if err != nil {
+		return 0, err
+	}
+	authors[email] = user.ID
+	return user.ID, nil
+}
+
+// Load adds the sample dataset, leaving the records already present alone
+func Load(db *gorm.DB) (Result, error) {
+	return Apply(db, &Sample, true)
 }
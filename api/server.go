@@ -1,29 +1,257 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/config"
 	"github.com/planutim/postgres-copy/api/controllers"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/logging"
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/api/models"
 	"github.com/planutim/postgres-copy/api/seed"
+	"github.com/planutim/postgres-copy/api/tracing"
+	"github.com/planutim/postgres-copy/api/workers"
 )
 
 var server = controllers.Server{}
 
-func Run() {
-	var err error
-	err = godotenv.Load()
+// configure parses the command line of a subcommand along with the configuration
+// flags and applies the configuration to the packages reading it
+func configure(flags *flag.FlagSet, args []string) *config.Config {
+	loader := config.NewLoader(flags)
+	flags.Parse(args)
+	return apply(loader)
+}
+
+func apply(loader *config.Loader) *config.Config {
+	cfg, err := loader.Load()
+	if err != nil {
+		fatal("cannot load the configuration", err)
+	}
+	err = logging.Setup(os.Stdout, cfg.Log.Level)
 	if err != nil {
-		log.Fatalf("Error getting env, not coming through %v", err)
-	} else {
-		fmt.Println("We are getting the env values")
+		fatal("cannot set up the logs", err)
 	}
+	auth.Configure(cfg.Auth.Secret, cfg.Auth.TokenTTL)
+	models.MaxContentLength = cfg.Posts.MaxContentLength
+	models.LinkPrefix = controllers.APIPrefix
+	return cfg
+}
 
-	server.Initialize(os.Getenv("DB_DRIVER"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_PORT"), os.Getenv("DB_NAME"), os.Getenv("DB_HOST"))
+func connect(cfg *config.Config) {
+	err := server.Connect(cfg.DB, cfg.Log)
+	if err != nil {
+		fatal("cannot connect to the database", err)
+	}
+}
 
-	seed.Load(server.DB)
+// Run serves the API. Pending migrations are applied first unless DB_AUTO_MIGRATE
+// is false, nothing is ever dropped on startup.
+func Run(args []string) {
+	cfg := configure(flag.NewFlagSet("serve", flag.ExitOnError), args)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fatal("cannot set up tracing", err)
+	}
+	err = server.Initialize(cfg.DB, cfg.Log)
+	if err != nil {
+		fatal("cannot connect to the database", err)
+	}
+
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		fatal("cannot read the migrations", err)
+	}
+	if database.InMemory(cfg.DB) && !cfg.DB.AutoMigrate {
+		fatal("cannot start", errors.New("an in-memory database starts empty, DB_AUTO_MIGRATE cannot be false"))
+	}
+	if cfg.DB.AutoMigrate {
+		applied, err := migrator.Up()
+		if err != nil {
+			fatal("cannot migrate the database", err)
+		}
+		slog.Info("migrations applied", "count", applied)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		fatal("cannot read the migration status", err)
+	}
+	if pending > 0 {
+		slog.Warn("migrations are pending, run the migrate up command", "pending", pending)
+	}
+
+	server.Migrator = migrator
+	server.Workers = workers.NewGroup(
+		workers.NotificationPruner(server.DB, cfg.Notifications.Retention),
+	)
+	err = server.Run(cfg.Addr(), cfg.HTTP)
+	if err != nil {
+		fatal("the server stopped", err)
+	}
+	// The spans of the last requests are still to be exported
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	err = shutdownTracing(ctx)
+	if err != nil {
+		slog.Error("cannot export the last spans", "error", err)
+	}
+	slog.Info("stopped")
+}
+
+// Migrate runs the migrate command: up, down [steps|all], status or create <name>
+func Migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	loader := config.NewLoader(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) == 0 {
+		usage("migrate up|down [steps|all]|status|create <name> [dir]")
+	}
+	if args[0] == "create" {
+		if len(args) < 2 {
+			usage("migrate create <name> [dir]")
+		}
+		dir := "api/migrations"
+		if len(args) > 2 {
+			dir = args[2]
+		}
+		created, err := migrations.Create(dir, args[1])
+		if err != nil {
+			fatal("cannot create the migration", err)
+		}
+		fmt.Println(strings.Join(created, "\n"))
+		return
+	}
+
+	// Creating files needs no configuration, everything else connects to the database
+	cfg := apply(loader)
+	connect(cfg)
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		fatal("cannot read the migrations", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			fatal("cannot migrate the database", err)
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 && args[1] == "all" {
+			steps = int(^uint(0) >> 1)
+		} else if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				usage("migrate down [steps|all], steps being a positive number")
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		if err != nil {
+			fatal("cannot revert the migrations", err)
+		}
+		fmt.Printf("Reverted %d migrations\n", reverted)
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			fatal("cannot read the migration status", err)
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d %-40s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		usage("migrate up|down [steps|all]|status|create <name> [dir]")
+	}
+}
+
+// Seed runs the seed command. Without fixture files or generated records it loads
+// the sample dataset:
+//
+//	seed [-file fixture.yaml]... [-users N -posts N -tags N -seed S] [-upsert]
+func Seed(args []string) {
+	var files stringList
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	flags.Var(&files, "file", "YAML or JSON fixture file to load, may be repeated")
+	users := flags.Int("users", 0, "number of fake users to generate")
+	posts := flags.Int("posts", 0, "number of fake posts to generate")
+	tags := flags.Int("tags", 0, "number of fake tags to generate")
+	rngSeed := flags.Int64("seed", 1, "seed of the fake data generator, the same seed gives the same data")
+	password := flags.String("password", "password", "password of the generated users")
+	upsert := flags.Bool("upsert", false, "update records that already exist instead of failing, making runs repeatable")
+	cfg := configure(flags, args)
+
+	fixture := &seed.Fixture{}
+	for _, file := range files {
+		loaded, err := seed.ReadFixture(file)
+		if err != nil {
+			fatal("cannot read the fixture", err)
+		}
+		fixture.Merge(loaded)
+	}
+	if *users > 0 || *posts > 0 || *tags > 0 {
+		fixture.Merge(seed.Generate(seed.GenerateOptions{Users: *users, Posts: *posts, Tags: *tags, Seed: *rngSeed, Password: *password}))
+	}
+	if len(files) == 0 && *users == 0 && *posts == 0 && *tags == 0 {
+		fixture = &seed.Sample
+		*upsert = true
+	}
+
+	connect(cfg)
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		fatal("cannot read the migrations", err)
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		fatal("cannot read the migration status", err)
+	}
+	if pending > 0 {
+		fatal("cannot seed the database", fmt.Errorf("%d migrations are pending, run the migrate up command first", pending))
+	}
+	result, err := seed.Apply(server.DB, fixture, *upsert)
+	if err != nil {
+		fatal("cannot seed the database", err)
+	}
+	fmt.Println(result)
+}
+
+// fatal logs what stopped the command and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// usage prints how a command is used and exits
+func usage(text string) {
+	fmt.Fprintln(os.Stderr, "Usage: "+text)
+	os.Exit(2)
+}
+
+// stringList collects the values of a repeated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-	server.Run(":8082")
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
@@ -0,0 +1,76 @@
+// Package sitemap streams sitemap and sitemap index documents following sitemaps.org
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URLsPerFile is the most urls a single sitemap file lists, the protocol allows 50,000
+var URLsPerFile = 50000
+
+// Writer writes the entries of a sitemap or of a sitemap index one at a time, so
+// that documents of any size can be produced from a cursor
+type Writer struct {
+	enc   *xml.Encoder
+	root  xml.StartElement
+	entry string
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// NewURLSet starts a sitemap listing pages
+func NewURLSet(w io.Writer) (*Writer, error) {
+	return newWriter(w, "urlset", "url")
+}
+
+// NewIndex starts a sitemap index listing other sitemap files
+func NewIndex(w io.Writer) (*Writer, error) {
+	return newWriter(w, "sitemapindex", "sitemap")
+}
+
+func newWriter(w io.Writer, root, entry string) (*Writer, error) {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return nil, err
+	}
+	sw := &Writer{
+		enc: xml.NewEncoder(w),
+		root: xml.StartElement{
+			Name: xml.Name{Local: root},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: namespace}},
+		},
+		entry: entry,
+	}
+	return sw, sw.enc.EncodeToken(sw.root)
+}
+
+// Add writes one entry, the last modification is left out when unknown
+func (sw *Writer) Add(loc string, lastMod time.Time) error {
+	e := entry{Loc: loc}
+	if !lastMod.IsZero() {
+		e.LastMod = lastMod.UTC().Format(time.RFC3339)
+	}
+	// The encoder only buffers a few kilobytes before writing through
+	return sw.enc.EncodeElement(e, xml.StartElement{Name: xml.Name{Local: sw.entry}})
+}
+
+// Close ends the document
+func (sw *Writer) Close() error {
+	err := sw.enc.EncodeToken(sw.root.End())
+	if err != nil {
+		return err
+	}
+	return sw.enc.Flush()
+}
+
+// Files is the number of sitemap files needed for count urls
+func Files(count int) int {
+	return (count + URLsPerFile - 1) / URLsPerFile
+}
@@ -0,0 +1,89 @@
+// Package syndication writes lists of posts as RSS 2.0, Atom 1.0 and JSON Feed 1.1 documents
+package syndication
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// ContentTypes maps every supported format to the media type it is served with
+var ContentTypes = map[string]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// Feed is the format independent description of a feed, links are absolute urls
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	FeedLink    string
+	Items       []Item
+}
+
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Summary     string
+	ContentHTML string
+	AuthorName  string
+	AuthorLink  string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Updated is the time the most recently changed item was updated, the zero time
+// for an empty feed
+func (f *Feed) Updated() time.Time {
+	var updated time.Time
+	for _, item := range f.Items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+	return updated
+}
+
+// ETag identifies the rendering of the feed in the given format, it changes
+// whenever an item is added, removed or updated
+func (f *Feed) ETag(format string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", format, f.FeedLink, f.Title)
+	for _, item := range f.Items {
+		fmt.Fprintf(h, "%s %d\n", item.ID, item.Updated.UnixNano())
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// Encode renders the feed in one of the supported formats
+func (f *Feed) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatRSS:
+		return f.rss()
+	case FormatAtom:
+		return f.atom()
+	case FormatJSON:
+		return f.jsonFeed()
+	}
+	return nil, fmt.Errorf("unknown feed format %q", format)
+}
+
+// updatedOrNow keeps the channel level dates of an empty feed valid
+func (f *Feed) updatedOrNow() time.Time {
+	updated := f.Updated()
+	if updated.IsZero() {
+		return time.Now()
+	}
+	return updated
+}
@@ -0,0 +1,59 @@
+package syndication
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+func (f *Feed) jsonFeed() ([]byte, error) {
+	doc := jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedLink,
+		Description: f.Description,
+		Items:       []jsonFeedItem{},
+	}
+	for _, item := range f.Items {
+		doc.Items = append(doc.Items, jsonFeedItem{
+			ID:            item.ID,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			Summary:       item.Summary,
+			DatePublished: item.Published.UTC().Format(time.RFC3339),
+			DateModified:  item.Updated.UTC().Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: item.AuthorName, URL: item.AuthorLink}},
+			Tags:          item.Tags,
+		})
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -0,0 +1,145 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func (f *Feed) rss() ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         f.Title,
+			Link:          f.Link,
+			Description:   f.Description,
+			Self:          atomLink{Href: f.FeedLink, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: f.updatedOrNow().UTC().Format(time.RFC1123Z),
+		},
+	}
+	for _, item := range f.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			GUID:        rssGUID{IsPermaLink: false, Value: item.ID},
+			Description: item.ContentHTML,
+			Author:      item.AuthorName,
+			Categories:  item.Tags,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+	return encodeXML(doc)
+}
+
+func (f *Feed) atom() ([]byte, error) {
+	doc := atomFeed{
+		ID:       f.FeedLink,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.updatedOrNow().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.FeedLink, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate"},
+		},
+	}
+	for _, item := range f.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      atomLink{Href: item.Link, Rel: "alternate"},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Author:    atomAuthor{Name: item.AuthorName, URI: item.AuthorLink},
+			Summary:   atomText{Type: "text", Value: item.Summary},
+			Content:   atomText{Type: "html", Value: item.ContentHTML},
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return encodeXML(doc)
+}
+
+func encodeXML(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
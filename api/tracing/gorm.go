@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/database"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	contextKey = "tracing:context"
+	spanKey    = "tracing:span"
+)
+
+// WithContext is db with the context its queries are traced in, gorm itself knows
+// nothing of contexts. The queries of a request become children of its span.
+func WithContext(db *gorm.DB, ctx context.Context) *gorm.DB {
+	return db.Set(contextKey, ctx)
+}
+
+// InstrumentDB starts a client span for every query gorm runs on db. Statements are
+// recorded with their placeholders, never with their values.
+func InstrumentDB(db *gorm.DB) {
+	system := db.Dialect().GetName()
+	database.RegisterHooks(db, "tracing", func(scope *gorm.Scope, operation string) {
+		ctx := context.Background()
+		if value, ok := scope.Get(contextKey); ok {
+			if c, ok := value.(context.Context); ok {
+				ctx = c
+			}
+		}
+		table := scope.TableName()
+		_, span := tracer().Start(ctx, operation+" "+table, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			semconv.DBSystemKey.String(system),
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(table),
+		))
+		scope.InstanceSet(spanKey, span)
+	}, endSpan)
+}
+
+func endSpan(scope *gorm.Scope, operation string) {
+	value, ok := scope.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	span.SetAttributes(attribute.String("db.query.text", scope.SQL), attribute.Int64("db.rows_affected", scope.DB().RowsAffected))
+	if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+		span.RecordError(scope.DB().Error)
+		span.SetStatus(codes.Error, scope.DB().Error.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/planutim/postgres-copy/api/requestid"
+	"github.com/planutim/postgres-copy/api/utils/route"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span per request of a mux router, named after the
+// method and the route template, as a child of the traceparent sent by the caller.
+// The traceparent of the span is sent back in the response headers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		propagator := otel.GetTextMapPropagator()
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		template := route.Template(r)
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.HTTPRoute(template),
+			semconv.URLPath(r.URL.Path),
+		}
+		if id := requestid.FromContext(ctx); id != "" {
+			attrs = append(attrs, attribute.String("request.id", id))
+		}
+		ctx, span := tracer().Start(ctx, r.Method+" "+template, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+		propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+
+		recorder := route.NewRecorder(w)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.Status()))
+		if recorder.Status() >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(recorder.Status()))
+		}
+	})
+}
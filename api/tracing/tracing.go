@@ -0,0 +1,94 @@
+// Package tracing creates the OpenTelemetry spans of the API, one per HTTP request
+// and one per database query, and exports them.
+//
+// Incoming W3C traceparent headers are honoured, so the spans of a request join the
+// trace of its caller. The OTLP exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* variables and the service name by OTEL_SERVICE_NAME.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/planutim/postgres-copy/api/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/planutim/postgres-copy/api"
+
+// ServiceName names the API in the traces unless OTEL_SERVICE_NAME is set
+const ServiceName = "postgres-copy-api"
+
+func init() {
+	// Propagation works even without an exporter, traces go through unchanged
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup installs the tracer provider exporting to the configured exporter. The
+// returned function flushes the spans left and has to be called before exiting.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var file io.Closer
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		w := io.Writer(os.Stdout)
+		if cfg.File != "" {
+			f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return nil, err
+			}
+			w, file = f, f
+		}
+		e, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, err
+		}
+		exporter = e
+	case "otlp":
+		e, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		exporter = e
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	// The environment wins over the default service name
+	res, err = resource.Merge(res, resource.Environment())
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}, nil
+}
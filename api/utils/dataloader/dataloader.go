@@ -0,0 +1,73 @@
+// Package dataloader batches lookups by key. The keys asked for before any result
+// is needed are fetched in one call, so that resolving a field of every item of a
+// list costs one query instead of one per item.
+package dataloader
+
+import "sync"
+
+// Loader loads values by key, caching them for its lifetime. A loader lives as long
+// as the request it serves, values are never stale for longer than that.
+type Loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	fetched map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+// New is a loader fetching the values of keys with fetch. Keys missing from the
+// map fetch returns have no value.
+func New[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		queued:  make(map[K]bool),
+		fetched: make(map[K]bool),
+		values:  make(map[K]V),
+		errs:    make(map[K]error),
+	}
+}
+
+// Load queues key and returns a thunk giving its value. The first thunk called
+// fetches every key queued so far, the value is only found when ok is true.
+func (l *Loader[K, V]) Load(key K) func() (value V, ok bool, err error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !l.fetched[key] {
+			l.flush()
+		}
+		if err := l.errs[key]; err != nil {
+			var zero V
+			return zero, false, err
+		}
+		value, ok := l.values[key]
+		return value, ok, nil
+	}
+}
+
+// flush fetches the pending keys, a failed fetch fails every key of the batch
+func (l *Loader[K, V]) flush() {
+	keys := l.pending
+	l.pending = nil
+	values, err := l.fetch(keys)
+	for _, k := range keys {
+		l.fetched[k] = true
+		if err != nil {
+			l.errs[k] = err
+			continue
+		}
+		if v, ok := values[k]; ok {
+			l.values[k] = v
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Page is a limit/offset window read from the query string
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// FromRequest reads ?limit= and ?offset= from the request, applying the defaults
+func FromRequest(r *http.Request) (Page, error) {
+	limit, err := LimitFromRequest(r)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Limit: limit}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		page.Offset, err = strconv.Atoi(v)
+		if err != nil || page.Offset < 0 {
+			return Page{}, errors.New("Invalid Offset")
+		}
+	}
+	return page, nil
+}
+
+// LimitFromRequest reads ?limit= from the request, capped at MaxLimit
+func LimitFromRequest(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return 0, errors.New("Invalid Limit")
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit, nil
+}
+
+// Cursor points at the last item of a page ordered by time and id, newest first
+type Cursor struct {
+	Time time.Time
+	ID   uint64
+}
+
+func (c Cursor) IsZero() bool {
+	return c.ID == 0
+}
+
+// Encode returns the opaque string handed out to clients
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%s|%d", c.Time.UTC().Format(time.RFC3339Nano), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a string produced by Cursor.Encode, an empty string is the zero cursor
+func DecodeCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("Invalid Cursor")
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return Cursor{}, errors.New("Invalid Cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Cursor{}, errors.New("Invalid Cursor")
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || id == 0 {
+		return Cursor{}, errors.New("Invalid Cursor")
+	}
+	return Cursor{Time: t.Local(), ID: id}, nil
+}
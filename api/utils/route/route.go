@@ -0,0 +1,66 @@
+// Package route tells the middlewares which route served a request and how it was
+// answered.
+package route
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Unmatched labels the requests no route matched
+const Unmatched = "unmatched"
+
+// Template is the path template of the mux route matching the request, such as
+// /posts/{id}, so that requests of a route share their labels whatever their ids
+func Template(r *http.Request) string {
+	current := mux.CurrentRoute(r)
+	if current == nil {
+		return Unmatched
+	}
+	template, err := current.GetPathTemplate()
+	if err != nil {
+		return Unmatched
+	}
+	return template
+}
+
+// Recorder remembers the status and the size of the response written through it
+type Recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func NewRecorder(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+func (rr *Recorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *Recorder) Write(b []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	return n, err
+}
+
+// Status is the status sent, 200 when the handler wrote nothing
+func (rr *Recorder) Status() int {
+	if rr.status == 0 {
+		return http.StatusOK
+	}
+	return rr.status
+}
+
+// Bytes is the size of the body written so far
+func (rr *Recorder) Bytes() int {
+	return rr.bytes
+}
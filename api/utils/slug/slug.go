@@ -0,0 +1,84 @@
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength keeps slugs readable, longer titles are cut at a word boundary
+const MaxLength = 80
+
+// Fallback is used for titles without a single transliterable character
+const Fallback = "post"
+
+// transliterations covers the letters that do not decompose into a latin base
+// letter plus combining marks
+var transliterations = map[rune]string{
+	// Latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "u",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Make turns a title into a lower case, ASCII only, dash separated slug
+func Make(title string) string {
+	var b strings.Builder
+	dash := false
+	write := func(r rune) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+			return
+		}
+		if unicode.Is(unicode.Mn, r) {
+			// combining marks left over from the decomposition, é becomes e
+			return
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	lookup := func(r rune) bool {
+		t, ok := transliterations[r]
+		if ok {
+			b.WriteString(t)
+			dash = dash && t == ""
+		}
+		return ok
+	}
+	for _, r := range norm.NFC.String(strings.ToLower(title)) {
+		// the table is checked before decomposing so that й is not read as и,
+		// and again after it so that accented greek letters such as έ are found
+		if lookup(r) {
+			continue
+		}
+		for _, d := range norm.NFD.String(string(r)) {
+			if !lookup(d) {
+				write(d)
+			}
+		}
+	}
+	s := strings.Trim(b.String(), "-")
+	if len(s) > MaxLength {
+		s = s[:MaxLength]
+		if i := strings.LastIndexByte(s, '-'); i > 0 {
+			s = s[:i]
+		}
+		s = strings.Trim(s, "-")
+	}
+	if s == "" {
+		return Fallback
+	}
+	return s
+}
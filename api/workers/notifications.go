@@ -0,0 +1,23 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/models"
+)
+
+// NotificationPruner deletes, every hour, the notifications read longer than
+// retention ago. Stopping the worker interrupts a prune in progress.
+func NotificationPruner(db *gorm.DB, retention time.Duration) *Worker {
+	return &Worker{
+		Name:     "notification-pruner",
+		Interval: time.Hour,
+		Job: func(ctx context.Context) error {
+			notification := models.Notification{}
+			_, err := notification.PruneRead(ctx, db, time.Now().Add(-retention))
+			return err
+		},
+	}
+}
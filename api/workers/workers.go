@@ -0,0 +1,136 @@
+// Package workers runs the periodic background jobs of the API and stops them with
+// the server.
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is one run of a worker, it should return soon after ctx is cancelled
+type Job func(ctx context.Context) error
+
+// Worker runs its job every interval, starting right away
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Job      Job
+
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+	lastErr error
+}
+
+// Health is the state of a worker, as reported by the readiness check
+type Health struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	LastRun   *time.Time `json:"last_run"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Healthy tells whether the worker is running and its last run succeeded
+func (h Health) Healthy() bool {
+	return h.Running && h.LastError == ""
+}
+
+func (w *Worker) run(ctx context.Context) {
+	w.setRunning(true)
+	defer w.setRunning(false)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		err := w.Job(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("worker failed", "worker", w.Name, "error", err)
+		}
+		w.mu.Lock()
+		w.lastRun = time.Now()
+		w.lastErr = err
+		w.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) setRunning(running bool) {
+	w.mu.Lock()
+	w.running = running
+	w.mu.Unlock()
+}
+
+func (w *Worker) health() Health {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	h := Health{Name: w.Name, Running: w.running}
+	if !w.lastRun.IsZero() {
+		lastRun := w.lastRun
+		h.LastRun = &lastRun
+	}
+	if w.lastErr != nil {
+		h.LastError = w.lastErr.Error()
+	}
+	return h
+}
+
+// Group starts and stops a set of workers together
+type Group struct {
+	workers []*Worker
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewGroup(workers ...*Worker) *Group {
+	return &Group{workers: workers}
+}
+
+// Start runs every worker in its own goroutine until Stop is called
+func (g *Group) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	for _, w := range g.workers {
+		// Marked running before returning, so a readiness check right after Start passes
+		w.setRunning(true)
+		g.wg.Add(1)
+		go func(w *Worker) {
+			defer g.wg.Done()
+			w.run(ctx)
+		}(w)
+	}
+}
+
+// Stop cancels the running jobs and waits for them to return, or for ctx to be done
+func (g *Group) Stop(ctx context.Context) error {
+	if g.cancel == nil {
+		return nil
+	}
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Health reports the state of every worker, in the order they were added
+func (g *Group) Health() []Health {
+	health := []Health{}
+	for _, w := range g.workers {
+		health = append(health, w.health())
+	}
+	return health
+}
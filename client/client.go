@@ -0,0 +1,308 @@
+// Package client calls the API from Go. It signs in with the credentials it is
+// given and signs in again before its token expires, retries the requests the
+// server failed and reports problem details as *Error values.
+//
+//	c := client.New("http://localhost:8080", client.Options{Email: email, Password: password})
+//	post, err := c.GetPost(ctx, 1)
+//	if errors.Is(err, client.ErrNotFound) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// apiPrefix is the version of the API the client is written for
+	apiPrefix = "/api/v1"
+
+	defaultMaxRetries = 3
+	defaultBackoff    = 100 * time.Millisecond
+	defaultMaxBackoff = 2 * time.Second
+	// refreshMargin is how long before it expires a token is replaced, so that it
+	// does not expire on its way to the server
+	refreshMargin = 30 * time.Second
+)
+
+// Options configure a client, the zero value of each option picks its default
+type Options struct {
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// Email and Password sign the client in whenever it needs a token. Without
+	// them only the token of Login or SetToken is sent, and never renewed.
+	Email    string
+	Password string
+	// MaxRetries is how many times a request failing with a 5xx status or a
+	// network error is retried, 3 when zero. A negative value disables retries.
+	MaxRetries int
+	// Backoff is the wait before the first retry, 100ms when zero. It doubles with
+	// each retry up to MaxBackoff, 2s when zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client calls the API, it is safe for concurrent use
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	email    string
+	password string
+	token    string
+	expires  time.Time
+}
+
+// New is a client of the API served at baseURL, such as http://localhost:8080
+func New(baseURL string, options Options) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: options.HTTPClient,
+		maxRetries: options.MaxRetries,
+		backoff:    options.Backoff,
+		maxBackoff: options.MaxBackoff,
+		email:      options.Email,
+		password:   options.Password,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = defaultMaxRetries
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.backoff <= 0 {
+		c.backoff = defaultBackoff
+	}
+	if c.maxBackoff <= 0 {
+		c.maxBackoff = defaultMaxBackoff
+	}
+	return c
+}
+
+// Login signs in and keeps the credentials to sign in again when the token expires.
+// The token is returned for callers passing it on.
+func (c *Client) Login(ctx context.Context, email, password string) (string, error) {
+	token, err := c.login(ctx, email, password)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.email, c.password = email, password
+	c.mu.Unlock()
+	return token, nil
+}
+
+// SetToken makes the client send token, a client without credentials cannot
+// renew it
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.expires = token, expiry(token)
+}
+
+// Token is the token the client currently sends, empty before signing in
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+func (c *Client) login(ctx context.Context, email, password string) (string, error) {
+	credentials := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+	var token string
+	// Signing in changes nothing on the server, it can be retried like a read
+	err := c.do(ctx, request{method: "POST", path: apiPrefix + "/login", body: credentials, idempotent: true}, &token)
+	if err != nil {
+		return "", err
+	}
+	c.SetToken(token)
+	return token, nil
+}
+
+// relogin signs in again with the credentials of the client
+func (c *Client) relogin(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	email, password := c.email, c.password
+	c.mu.Unlock()
+	return c.login(ctx, email, password)
+}
+
+// expiry reads the expiration time of a token. The client cannot check the
+// signature, the server does.
+func expiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(token, claims)
+	if err != nil {
+		return time.Time{}
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(exp), 0)
+}
+
+// authToken is a token valid for a while longer, signing in again when the
+// current one is about to expire and the client has credentials
+func (c *Client) authToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, expires, canLogin := c.token, c.expires, c.email != ""
+	c.mu.Unlock()
+	stale := token == "" || (!expires.IsZero() && time.Until(expires) < refreshMargin)
+	if stale && canLogin {
+		return c.relogin(ctx)
+	}
+	if token == "" {
+		return "", &Error{StatusCode: http.StatusUnauthorized, Kind: ErrUnauthorized.Kind, Detail: "Not signed in"}
+	}
+	return token, nil
+}
+
+// request is a call to the API
+type request struct {
+	method string
+	path   string
+	// body is encoded to JSON when not nil
+	body interface{}
+	auth bool
+	// idempotent requests are retried when the server fails
+	idempotent bool
+}
+
+// call sends a request signed in when it needs to be, signing in again once when
+// the server rejects the token
+func (c *Client) call(ctx context.Context, req request, out interface{}) error {
+	err := c.do(ctx, req, out)
+	if !req.auth || !errors.Is(err, ErrUnauthorized) {
+		return err
+	}
+	c.mu.Lock()
+	canLogin := c.email != ""
+	c.mu.Unlock()
+	if !canLogin {
+		return err
+	}
+	// The token may have been revoked or signed with a key the server replaced
+	_, loginErr := c.relogin(ctx)
+	if loginErr != nil {
+		return loginErr
+	}
+	return c.do(ctx, req, out)
+}
+
+// do sends a request, retrying idempotent ones on network errors and 5xx
+// statuses, and decodes the response into out
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		payload, err = json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	retries := 0
+	if req.idempotent {
+		retries = c.maxRetries
+	}
+	wait := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.send(ctx, req, payload, out)
+		if err == nil || attempt >= retries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		// Full jitter spreads the retries of clients failing at the same time
+		timer := time.NewTimer(time.Duration(rand.Int63n(int64(wait)) + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		wait *= 2
+		if wait > c.maxBackoff {
+			wait = c.maxBackoff
+		}
+	}
+}
+
+// retryable tells whether a request may succeed if sent again: the server failed
+// or the request did not reach it
+func retryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode >= 500
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func (c *Client) send(ctx context.Context, req request, payload []byte, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, body)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if req.auth {
+		token, err := c.authToken(ctx)
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return decodeError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	err = json.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		return &DecodeError{Method: req.method, Path: req.path, Err: err}
+	}
+	return nil
+}
+
+// signedIn tells whether the client has a token or can get one, reads send it
+// when so to see what only the user can see
+func (c *Client) signedIn() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token != "" || c.email != ""
+}
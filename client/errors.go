@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/planutim/postgres-copy/api/apperrors"
+)
+
+// Error is an error response of the API, read from its problem details
+type Error struct {
+	StatusCode int
+	Kind       apperrors.Kind
+	Title      string
+	Detail     string
+	// RequestID identifies the request in the logs of the server
+	RequestID string
+	// Fields are the invalid fields of a validation or conflict error
+	Fields []apperrors.FieldError
+}
+
+// The kinds of errors, to compare with errors.Is:
+//
+//	if errors.Is(err, client.ErrConflict) {
+var (
+	ErrNotFound     = &Error{Kind: apperrors.NotFound}
+	ErrConflict     = &Error{Kind: apperrors.Conflict}
+	ErrValidation   = &Error{Kind: apperrors.Validation}
+	ErrUnauthorized = &Error{Kind: apperrors.Unauthorized}
+	ErrForbidden    = &Error{Kind: apperrors.Forbidden}
+	// ErrServer is a failure of the server, the request was retried if it could be
+	ErrServer = &Error{Kind: apperrors.Internal}
+)
+
+func (e *Error) Error() string {
+	message := e.Detail
+	if message == "" {
+		message = e.Title
+	}
+	if e.RequestID != "" {
+		return fmt.Sprintf("%d %s (request %s)", e.StatusCode, message, e.RequestID)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, message)
+}
+
+// Is matches the error of the same kind among the errors above. Only 5xx
+// statuses are server errors, other statuses of no kind match none.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || t.StatusCode != 0 || t.Kind != e.Kind {
+		return false
+	}
+	return e.Kind != apperrors.Internal || e.StatusCode >= 500
+}
+
+// DecodeError is a successful response the client cannot read, sending the request
+// again would not help
+type DecodeError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cannot read the response to %s %s: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeError reads the problem details of an error response. Responses that have
+// none, such as those of a proxy, keep their status.
+func decodeError(resp *http.Response) error {
+	e := &Error{
+		StatusCode: resp.StatusCode,
+		Kind:       kindOfStatus(resp.StatusCode),
+		Title:      http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+	var problem struct {
+		Type      string                 `json:"type"`
+		Title     string                 `json:"title"`
+		Detail    string                 `json:"detail"`
+		RequestID string                 `json:"request_id"`
+		Errors    []apperrors.FieldError `json:"errors"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, &problem) != nil {
+		e.Detail = strings.TrimSpace(string(data))
+		return e
+	}
+	if problem.Title != "" {
+		e.Title = problem.Title
+	}
+	if problem.RequestID != "" {
+		e.RequestID = problem.RequestID
+	}
+	e.Detail = problem.Detail
+	e.Fields = problem.Errors
+	if kind, ok := kindOfSlug(strings.TrimPrefix(problem.Type, "/problems/")); ok {
+		e.Kind = kind
+	}
+	return e
+}
+
+var errorKinds = []apperrors.Kind{
+	apperrors.NotFound, apperrors.Conflict, apperrors.Validation,
+	apperrors.Unauthorized, apperrors.Forbidden, apperrors.Internal,
+}
+
+// kindOfSlug is the kind named in a problem type
+func kindOfSlug(slug string) (apperrors.Kind, bool) {
+	for _, kind := range errorKinds {
+		if kind.Slug() == slug {
+			return kind, true
+		}
+	}
+	return apperrors.Internal, false
+}
+
+// kindOfStatus is the kind of an error response without a problem type
+func kindOfStatus(status int) apperrors.Kind {
+	if status == http.StatusBadRequest {
+		return apperrors.Validation
+	}
+	for _, kind := range errorKinds {
+		if kind.Status() == status {
+			return kind
+		}
+	}
+	return apperrors.Internal
+}
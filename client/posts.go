@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// The statuses of a post
+const (
+	PostDraft     = "draft"
+	PostPublished = "published"
+)
+
+type Post struct {
+	ID       uint64 `json:"id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Content  string `json:"content"`
+	Excerpt  string `json:"excerpt"`
+	Author   User   `json:"author"`
+	AuthorID uint32 `json:"author_id"`
+	Status   string `json:"status"`
+	// Reactions count each type of reaction, MyReactions are those of the user the
+	// client is signed in with
+	Reactions          map[string]int64 `json:"reactions"`
+	MyReactions        []string         `json:"my_reactions"`
+	Tags               []string         `json:"tags"`
+	Mentions           []string         `json:"mentions"`
+	ContentHTML        string           `json:"content_html"`
+	ContentText        string           `json:"content_text"`
+	WordCount          int              `json:"word_count"`
+	ReadingTimeMinutes int              `json:"reading_time_minutes"`
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
+}
+
+// PostInput creates or updates a post. AuthorID is the ID of the user the client is
+// signed in with, an empty Status publishes the post.
+type PostInput struct {
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Excerpt  string `json:"excerpt,omitempty"`
+	AuthorID uint32 `json:"author_id"`
+	Status   string `json:"status,omitempty"`
+}
+
+// CreatePost is not retried, a post created by a request the server failed to
+// answer would be created twice
+func (c *Client) CreatePost(ctx context.Context, input PostInput) (*Post, error) {
+	post := &Post{}
+	err := c.call(ctx, request{method: "POST", path: apiPrefix + "/posts", body: input, auth: true}, post)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// GetPosts lists the latest published posts
+func (c *Client) GetPosts(ctx context.Context) ([]Post, error) {
+	posts := []Post{}
+	err := c.call(ctx, request{method: "GET", path: apiPrefix + "/posts", idempotent: true}, &posts)
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetPost reads a post. Drafts are only found when the client is signed in as
+// their author.
+func (c *Client) GetPost(ctx context.Context, id uint64) (*Post, error) {
+	post := &Post{}
+	err := c.call(ctx, request{method: "GET", path: fmt.Sprintf("%s/posts/%d", apiPrefix, id), auth: c.signedIn(), idempotent: true}, post)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// GetPostBySlug reads a post by its slug, following the redirection of an old slug
+func (c *Client) GetPostBySlug(ctx context.Context, slug string) (*Post, error) {
+	post := &Post{}
+	err := c.call(ctx, request{method: "GET", path: apiPrefix + "/posts/by-slug/" + url.PathEscape(slug), auth: c.signedIn(), idempotent: true}, post)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (c *Client) UpdatePost(ctx context.Context, id uint64, input PostInput) (*Post, error) {
+	post := &Post{}
+	err := c.call(ctx, request{method: "PUT", path: fmt.Sprintf("%s/posts/%d", apiPrefix, id), body: input, auth: true, idempotent: true}, post)
+	if err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (c *Client) DeletePost(ctx context.Context, id uint64) error {
+	return c.call(ctx, request{method: "DELETE", path: fmt.Sprintf("%s/posts/%d", apiPrefix, id), auth: true, idempotent: true}, nil)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type User struct {
+	ID        uint32    `json:"id"`
+	Nickname  string    `json:"nickname"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserInput creates or updates a user, every field is required
+type UserInput struct {
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// CreateUser signs a user up, it needs no token
+func (c *Client) CreateUser(ctx context.Context, input UserInput) (*User, error) {
+	user := &User{}
+	err := c.call(ctx, request{method: "POST", path: apiPrefix + "/users", body: input}, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) GetUsers(ctx context.Context) ([]User, error) {
+	users := []User{}
+	err := c.call(ctx, request{method: "GET", path: apiPrefix + "/users", idempotent: true}, &users)
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, id uint32) (*User, error) {
+	user := &User{}
+	err := c.call(ctx, request{method: "GET", path: fmt.Sprintf("%s/users/%d", apiPrefix, id), idempotent: true}, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUser updates the account the client is signed in with
+func (c *Client) UpdateUser(ctx context.Context, id uint32, input UserInput) (*User, error) {
+	user := &User{}
+	err := c.call(ctx, request{method: "PUT", path: fmt.Sprintf("%s/users/%d", apiPrefix, id), body: input, auth: true, idempotent: true}, user)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// DeleteUser deletes the account the client is signed in with
+func (c *Client) DeleteUser(ctx context.Context, id uint32) error {
+	return c.call(ctx, request{method: "DELETE", path: fmt.Sprintf("%s/users/%d", apiPrefix, id), auth: true, idempotent: true}, nil)
+}
@@ -1,7 +1,38 @@
 package main
 
-import "github.com/planutim/postgres-copy/api"
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/planutim/postgres-copy/api"
+)
+
+const usage = `Usage: main [command] [flags]
+
+  serve                                   run the API (the default)
+  migrate up|down [steps|all]|status      manage the schema
+  migrate create <name> [dir]             add a new migration
+  seed [-file f.yaml] [-users N -posts N -tags N -seed S] [-upsert]
+                                          load fixtures or fake data
+
+Every command accepts the configuration flags, see serve -h`
 
 func main() {
-	api.Run()
+	command, args := "serve", os.Args[1:]
+	// Flags alone configure the default serve command
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	switch command {
+	case "serve":
+		api.Run(args)
+	case "migrate":
+		api.Migrate(args)
+	case "seed":
+		api.Seed(args)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
 }
@@ -0,0 +1,304 @@
+package clienttests
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/config"
+	"github.com/planutim/postgres-copy/api/controllers"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/client"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var server = controllers.Server{}
+
+func TestMain(m *testing.M) {
+	err := godotenv.Load("../../.env")
+	if err != nil {
+		log.Fatalf("Error getting env %v\n", err)
+	}
+	auth.Configure(os.Getenv("API_SECRET"), time.Hour)
+
+	db, err := database.Open(config.DBConfig{Driver: "sqlite3", Name: ":memory:", MaxOpenConns: 10, MaxIdleConns: 10})
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.DB = db
+	server.InitializeRouter()
+
+	os.Exit(m.Run())
+}
+
+// newAPI serves the router on an empty database, handler wraps it when not nil
+func newAPI(t *testing.T, handler func(http.Handler) http.Handler) *httptest.Server {
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Down(len(migrator.Migrations()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h http.Handler = server.Router
+	if handler != nil {
+		h = handler(h)
+	}
+	api := httptest.NewServer(h)
+	t.Cleanup(api.Close)
+	return api
+}
+
+// signUp creates a user and a client signed in as that user
+func signUp(t *testing.T, api *httptest.Server, input client.UserInput, options client.Options) (*client.User, *client.Client) {
+	ctx := context.Background()
+	user, err := client.New(api.URL, client.Options{}).CreateUser(ctx, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.Email, options.Password = input.Email, input.Password
+	return user, client.New(api.URL, options)
+}
+
+var pet = client.UserInput{Nickname: "Pet", Email: "pet@gmail.com", Password: "password"}
+var kenny = client.UserInput{Nickname: "Kenny", Email: "kenny@gmail.com", Password: "password"}
+
+func TestUsers(t *testing.T) {
+	api := newAPI(t, nil)
+	ctx := context.Background()
+	user, c := signUp(t, api, pet, client.Options{})
+	other, _ := signUp(t, api, kenny, client.Options{})
+
+	found, err := c.GetUser(ctx, user.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, found.Nickname, "Pet")
+
+	users, err := c.GetUsers(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(users), 2)
+
+	updated, err := c.UpdateUser(ctx, user.ID, client.UserInput{Nickname: "Pet Shop", Email: "pet@gmail.com", Password: "password"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, updated.Nickname, "Pet Shop")
+
+	_, err = c.UpdateUser(ctx, other.ID, kenny)
+	assert.Equal(t, errors.Is(err, client.ErrForbidden), true)
+
+	_, err = c.CreateUser(ctx, kenny)
+	assert.Equal(t, errors.Is(err, client.ErrConflict), true)
+	var apiErr *client.Error
+	assert.Equal(t, errors.As(err, &apiErr), true)
+	assert.Equal(t, apiErr.StatusCode, http.StatusConflict)
+	assert.NotEqual(t, apiErr.RequestID, "")
+	assert.NotEqual(t, len(apiErr.Fields), 0)
+
+	_, err = c.CreateUser(ctx, client.UserInput{})
+	assert.Equal(t, errors.Is(err, client.ErrValidation), true)
+	assert.Equal(t, errors.As(err, &apiErr), true)
+	assert.Equal(t, len(apiErr.Fields), 3)
+
+	err = c.DeleteUser(ctx, user.ID)
+	assert.Equal(t, err, nil)
+	_, err = c.GetUser(ctx, user.ID)
+	assert.Equal(t, errors.Is(err, client.ErrNotFound), true)
+	assert.Equal(t, errors.Is(err, client.ErrServer), false)
+}
+
+func TestPosts(t *testing.T) {
+	api := newAPI(t, nil)
+	ctx := context.Background()
+	user, c := signUp(t, api, pet, client.Options{})
+	other, _ := signUp(t, api, kenny, client.Options{})
+	anonymous := client.New(api.URL, client.Options{})
+
+	post, err := c.CreatePost(ctx, client.PostInput{Title: "Hello", Content: "Hello #go", AuthorID: user.ID})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, post.Author.ID, user.ID)
+	assert.Equal(t, post.Tags, []string{"go"})
+
+	_, err = c.CreatePost(ctx, client.PostInput{Title: "Impostor", Content: "Content", AuthorID: other.ID})
+	assert.Equal(t, errors.Is(err, client.ErrForbidden), true)
+	_, err = anonymous.CreatePost(ctx, client.PostInput{Title: "Nobody", Content: "Content", AuthorID: user.ID})
+	assert.Equal(t, errors.Is(err, client.ErrUnauthorized), true)
+
+	updated, err := c.UpdatePost(ctx, post.ID, client.PostInput{Title: "Hello again", Content: "Updated", AuthorID: user.ID})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, updated.Title, "Hello again")
+
+	// The old slug redirects to the new one
+	found, err := anonymous.GetPostBySlug(ctx, post.Slug)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, found.ID, post.ID)
+	assert.Equal(t, found.Slug, updated.Slug)
+
+	draft, err := c.CreatePost(ctx, client.PostInput{Title: "Draft", Content: "Secret", AuthorID: user.ID, Status: client.PostDraft})
+	assert.Equal(t, err, nil)
+	_, err = c.GetPost(ctx, draft.ID)
+	assert.Equal(t, err, nil)
+	_, err = anonymous.GetPost(ctx, draft.ID)
+	assert.Equal(t, errors.Is(err, client.ErrNotFound), true)
+
+	posts, err := anonymous.GetPosts(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(posts), 1)
+
+	err = c.DeletePost(ctx, post.ID)
+	assert.Equal(t, err, nil)
+	_, err = c.GetPost(ctx, post.ID)
+	assert.Equal(t, errors.Is(err, client.ErrNotFound), true)
+}
+
+func TestTokenRefresh(t *testing.T) {
+	api := newAPI(t, nil)
+	ctx := context.Background()
+	user, c := signUp(t, api, pet, client.Options{})
+
+	// No token yet, the client signs in on its first request
+	_, err := c.UpdateUser(ctx, user.ID, pet)
+	assert.Equal(t, err, nil)
+	first := c.Token()
+	assert.NotEqual(t, first, "")
+
+	// An expired token is replaced before it is sent
+	auth.Configure(os.Getenv("API_SECRET"), -time.Minute)
+	expired, err := auth.CreateToken(user.ID)
+	auth.Configure(os.Getenv("API_SECRET"), time.Hour)
+	assert.Equal(t, err, nil)
+	c.SetToken(expired)
+	_, err = c.UpdateUser(ctx, user.ID, pet)
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, c.Token(), expired)
+
+	// A token the server rejects although it has not expired is replaced once
+	auth.Configure("another secret", time.Hour)
+	rejected, err := auth.CreateToken(user.ID)
+	auth.Configure(os.Getenv("API_SECRET"), time.Hour)
+	assert.Equal(t, err, nil)
+	c.SetToken(rejected)
+	_, err = c.UpdateUser(ctx, user.ID, pet)
+	assert.Equal(t, err, nil)
+	assert.NotEqual(t, c.Token(), rejected)
+
+	// Without credentials the token cannot be replaced
+	tokenOnly := client.New(api.URL, client.Options{})
+	tokenOnly.SetToken(rejected)
+	_, err = tokenOnly.UpdateUser(ctx, user.ID, pet)
+	assert.Equal(t, errors.Is(err, client.ErrUnauthorized), true)
+
+	// Login keeps the credentials, so the token can be replaced afterwards
+	loggedIn := client.New(api.URL, client.Options{})
+	token, err := loggedIn.Login(ctx, pet.Email, pet.Password)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, loggedIn.Token(), token)
+	loggedIn.SetToken(rejected)
+	_, err = loggedIn.UpdateUser(ctx, user.ID, pet)
+	assert.Equal(t, err, nil)
+
+	// Wrong credentials are not kept
+	_, err = loggedIn.Login(ctx, pet.Email, "wrong")
+	assert.Equal(t, errors.Is(err, client.ErrUnauthorized), true)
+	loggedIn.SetToken(rejected)
+	_, err = loggedIn.UpdateUser(ctx, user.ID, pet)
+	assert.Equal(t, err, nil)
+}
+
+// failing answers the first failures requests with a 502, as a proxy would
+func failing(failures int32, requests *int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(requests, 1) <= failures {
+				http.Error(w, "upstream is restarting", http.StatusBadGateway)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	samples := []struct {
+		failures   int32
+		maxRetries int
+		write      bool
+		requests   int32
+		err        error
+	}{
+		{failures: 2, maxRetries: 3, requests: 3},
+		{failures: 5, maxRetries: 3, requests: 4, err: client.ErrServer},
+		{failures: 1, maxRetries: -1, requests: 1, err: client.ErrServer},
+		// Creations are not retried, they could happen twice
+		{failures: 1, maxRetries: 3, write: true, requests: 1, err: client.ErrServer},
+	}
+	for _, v := range samples {
+		var requests int32
+		api := newAPI(t, failing(v.failures, &requests))
+		c := client.New(api.URL, client.Options{MaxRetries: v.maxRetries, Backoff: time.Millisecond})
+		var err error
+		if v.write {
+			_, err = c.CreateUser(context.Background(), pet)
+		} else {
+			_, err = c.GetPosts(context.Background())
+		}
+		assert.Equal(t, atomic.LoadInt32(&requests), v.requests)
+		if v.err == nil {
+			assert.Equal(t, err, nil)
+			continue
+		}
+		assert.Equal(t, errors.Is(err, v.err), true)
+		var apiErr *client.Error
+		assert.Equal(t, errors.As(err, &apiErr), true)
+		assert.Equal(t, apiErr.StatusCode, http.StatusBadGateway)
+		assert.Equal(t, apiErr.Detail, "upstream is restarting")
+	}
+}
+
+func TestUnreadableResponse(t *testing.T) {
+	var requests int32
+	api := newAPI(t, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("{not json"))
+		})
+	})
+	c := client.New(api.URL, client.Options{MaxRetries: 3, Backoff: time.Millisecond})
+
+	// The server answered, sending the request again would give the same response
+	_, err := c.GetPosts(context.Background())
+	var decodeErr *client.DecodeError
+	assert.Equal(t, errors.As(err, &decodeErr), true)
+	assert.Equal(t, decodeErr.Path, "/api/v1/posts")
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(1))
+}
+
+func TestContext(t *testing.T) {
+	var requests int32
+	api := newAPI(t, failing(100, &requests))
+	c := client.New(api.URL, client.Options{Backoff: time.Hour, MaxBackoff: time.Hour})
+
+	// The wait before the next retry ends with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.GetPosts(ctx)
+	assert.Equal(t, errors.Is(err, context.DeadlineExceeded), true)
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(1))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = c.GetPosts(ctx)
+	assert.Equal(t, errors.Is(err, context.Canceled), true)
+}
@@ -0,0 +1,145 @@
+package configtests
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/config"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func isConfigVariable(name string) bool {
+	return strings.HasPrefix(name, "DB_") || strings.HasPrefix(name, "API_") || strings.HasPrefix(name, "HTTP_") ||
+		name == "TOKEN_TTL" || name == "LOG_LEVEL" || name == "CONFIG_FILE" || name == "POST_MAX_CONTENT_LENGTH"
+}
+
+// clearConfigVariables unsets the configuration variables, including those a .env
+// file sets later on, and puts the original environment back after the test
+func clearConfigVariables(t *testing.T) {
+	saved := map[string]string{}
+	for _, kv := range os.Environ() {
+		pair := strings.SplitN(kv, "=", 2)
+		if isConfigVariable(pair[0]) {
+			saved[pair[0]] = pair[1]
+			os.Unsetenv(pair[0])
+		}
+	}
+	t.Cleanup(func() {
+		for _, kv := range os.Environ() {
+			name := strings.SplitN(kv, "=", 2)[0]
+			if isConfigVariable(name) {
+				os.Unsetenv(name)
+			}
+		}
+		for name, value := range saved {
+			os.Setenv(name, value)
+		}
+	})
+}
+
+// load reads the configuration with only the given environment, files and flags
+func load(t *testing.T, env map[string]string, args ...string) (*config.Config, error) {
+	clearConfigVariables(t)
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := config.NewLoader(flags)
+	err := flags.Parse(append([]string{"-env-file", filepath.Join(t.TempDir(), "missing.env")}, args...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader.Load()
+}
+
+func TestDefaults(t *testing.T) {
+	cfg, err := load(t, map[string]string{"API_SECRET": "secret", "DB_NAME": "api"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.Addr(), ":8082")
+	assert.Equal(t, cfg.DB.Driver, "postgres")
+	assert.Equal(t, cfg.Auth.TokenTTL, time.Hour)
+	assert.Equal(t, cfg.Log.Level, "info")
+	assert.Equal(t, cfg.DB.AutoMigrate, true)
+}
+
+func TestPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(yamlFile, []byte(`
+port: 7000
+log:
+  level: warn
+auth:
+  token_ttl: 2h
+db:
+  name: from_yaml
+  max_open_conns: 5
+  max_idle_conns: 2
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(dir, "test.env")
+	err = os.WriteFile(envFile, []byte("API_SECRET=from_env_file\nAPI_PORT=7100\nLOG_LEVEL=error\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// YAML < .env file < environment < flags
+	cfg, err := load(t, map[string]string{"API_PORT": "7200", "HTTP_READ_TIMEOUT": "3s"},
+		"-config", yamlFile, "-env-file", envFile, "-api-port", "7300")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.Port, 7300)
+	assert.Equal(t, cfg.HTTP.ReadTimeout, 3*time.Second)
+	assert.Equal(t, cfg.Log.Level, "error")
+	assert.Equal(t, cfg.Auth.Secret, "from_env_file")
+	assert.Equal(t, cfg.Auth.TokenTTL, 2*time.Hour)
+	assert.Equal(t, cfg.DB.Name, "from_yaml")
+	assert.Equal(t, cfg.DB.MaxOpenConns, 5)
+	assert.Equal(t, cfg.DB.MaxIdleConns, 2)
+
+	// The environment wins over the .env file
+	cfg, err = load(t, map[string]string{"API_SECRET": "from_env", "DB_NAME": "api"}, "-env-file", envFile)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, cfg.Auth.Secret, "from_env")
+}
+
+func TestValidate(t *testing.T) {
+	samples := []struct {
+		env      map[string]string
+		args     []string
+		problems []string
+	}{
+		{
+			env:      map[string]string{},
+			problems: []string{"DB_NAME is required", "API_SECRET is required"},
+		}, {
+			env:      map[string]string{"API_SECRET": "s", "DB_NAME": "api", "DB_DRIVER": "oracle"},
+			problems: []string{`DB_DRIVER "oracle" is not supported`},
+		}, {
+			env:      map[string]string{"API_SECRET": "s", "DB_NAME": "api"},
+			args:     []string{"-api-port", "0", "-log-level", "loud", "-token-ttl", "-1s", "-http-drain-delay", "-1s"},
+			problems: []string{"API_PORT must be between 1 and 65535", "TOKEN_TTL must be positive", `LOG_LEVEL "loud"`, "HTTP_DRAIN_DELAY must not be negative"},
+		}, {
+			env:      map[string]string{"API_SECRET": "s", "DB_NAME": "api", "DB_MAX_OPEN_CONNS": "2", "DB_MAX_IDLE_CONNS": "3"},
+			problems: []string{"DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS"},
+		}, {
+			env:      map[string]string{"API_SECRET": "s", "DB_NAME": "api", "HTTP_WRITE_TIMEOUT": "soon"},
+			problems: []string{`HTTP_WRITE_TIMEOUT: "soon" is not a duration`},
+		},
+	}
+	for _, v := range samples {
+		_, err := load(t, v.env, v.args...)
+		if err == nil {
+			t.Errorf("expected an error for %v %v", v.env, v.args)
+			continue
+		}
+		for _, problem := range v.problems {
+			assert.Equal(t, strings.Contains(err.Error(), problem), true)
+		}
+	}
+}
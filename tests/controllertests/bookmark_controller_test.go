@@ -0,0 +1,224 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestCreateBookmark(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	// a reading list to file bookmarks under
+	req, _ := http.NewRequest("POST", "/me/reading-lists", bytes.NewBufferString(`{"name": "Later"}`))
+	req.Header.Set("Authorization", tokenString)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreateReadingList).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	list := models.ReadingList{}
+	err = json.Unmarshal([]byte(rr.Body.String()), &list)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+
+	samples := []struct {
+		id            string
+		inputJSON     string
+		tokenGiven    string
+		statusCode    int
+		readingListID uint64
+		errorMessage  string
+	}{
+		{
+			id:         strconv.Itoa(int(post.ID)),
+			tokenGiven: tokenString,
+			statusCode: 201,
+		}, {
+			// bookmarking again moves the bookmark to the list
+			id:            strconv.Itoa(int(post.ID)),
+			inputJSON:     fmt.Sprintf(`{"reading_list_id": %d}`, list.ID),
+			tokenGiven:    tokenString,
+			statusCode:    200,
+			readingListID: list.ID,
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			inputJSON:    `{"reading_list_id": 100}`,
+			tokenGiven:   tokenString,
+			statusCode:   404,
+			errorMessage: "Reading list not found",
+		}, {
+			id:           "100",
+			tokenGiven:   tokenString,
+			statusCode:   404,
+			errorMessage: "Post not found",
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("POST", "/posts/bookmark", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.CreateBookmark).ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 || v.statusCode == 201 {
+			assert.Equal(t, responseMap["post_id"], float64(post.ID))
+			assert.Equal(t, responseMap["reading_list_id"], float64(v.readingListID))
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
+		}
+	}
+}
+
+func TestGetMyBookmarks(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, post := range posts {
+		bookmark := models.Bookmark{UserID: users[0].ID, PostID: post.ID}
+		err = server.DB.Model(&models.Bookmark{}).Create(&bookmark).Error
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	// the post of the second user goes back to draft
+	err = server.DB.Model(&models.Post{}).Where("id = ?", posts[1].ID).UpdateColumn("status", models.PostStatusDraft).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		query      string
+		tokenGiven string
+		statusCode int
+		length     int
+	}{
+		{
+			tokenGiven: tokenString,
+			statusCode: 200,
+			length:     2,
+		}, {
+			query:      "?limit=1&offset=1",
+			tokenGiven: tokenString,
+			statusCode: 200,
+			length:     1,
+		}, {
+			query:      "?list=100",
+			tokenGiven: tokenString,
+			statusCode: 200,
+			length:     0,
+		}, {
+			query:      "?list=abc",
+			tokenGiven: tokenString,
+			statusCode: 400,
+		}, {
+			tokenGiven: "",
+			statusCode: 401,
+		},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/me/bookmarks"+v.query, nil)
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetMyBookmarks).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			var bookmarks []models.Bookmark
+			err = json.Unmarshal([]byte(rr.Body.String()), &bookmarks)
+			if err != nil {
+				t.Errorf("Could not unmarshal: %v\n", err)
+			}
+			assert.Equal(t, len(bookmarks), v.length)
+			for _, bookmark := range bookmarks {
+				assert.Equal(t, bookmark.Available, bookmark.PostID == posts[0].ID)
+			}
+		}
+	}
+}
+
+func TestDeleteBookmark(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	bookmark := models.Bookmark{UserID: post.AuthorID, PostID: post.ID}
+	err = server.DB.Model(&models.Bookmark{}).Create(&bookmark).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		tokenGiven string
+		statusCode int
+	}{
+		{tokenGiven: tokenString, statusCode: 204},
+		{tokenGiven: tokenString, statusCode: 404},
+		{tokenGiven: "Wrong token", statusCode: 401},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("DELETE", "/posts/bookmark", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(post.ID))})
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.DeleteBookmark).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+}
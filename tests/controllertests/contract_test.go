@@ -0,0 +1,269 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/responses"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// The shapes a response body can have
+const (
+	shapeObject  = "object"
+	shapeArray   = "array"
+	shapeString  = "string"
+	shapeProblem = "problem"
+	shapeEmpty   = "empty"
+	shapeOther   = "other"
+)
+
+type contract struct {
+	method      string
+	path        string
+	body        string
+	token       string
+	statusCode  int
+	contentType string
+	shape       string
+	// headers must be sent back with a non empty value
+	headers []string
+	// keys must be in the object body
+	keys []string
+}
+
+// TestRouteContracts goes through every route of the router, in an order where each
+// request sees the records the previous ones created, and checks the status, the
+// headers and the shape of the body of its success and error paths. Every response
+// also has to match the OpenAPI document.
+func TestRouteContracts(t *testing.T) {
+	spec := loadSpec(t)
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.InitializeRouter()
+
+	me := fmt.Sprintf("/api/v1/users/%d", users[0].ID)
+	other := fmt.Sprintf("/api/v1/users/%d", users[1].ID)
+	mine := fmt.Sprintf("/api/v1/posts/%d", posts[0].ID)
+	theirs := fmt.Sprintf("/api/v1/posts/%d", posts[1].ID)
+	bearer := "Bearer " + token
+	jsonType := "application/json"
+	rssType := "application/rss+xml; charset=utf-8"
+	xmlType := "application/xml; charset=utf-8"
+
+	samples := []contract{
+		// Home and probes
+		{method: "GET", path: "/", statusCode: 200, contentType: jsonType, shape: shapeString},
+		{method: "GET", path: "/healthz", statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"status"}},
+		{method: "GET", path: "/readyz", statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"status", "checks"}},
+		{method: "GET", path: "/metrics", statusCode: 200, contentType: "text/plain", shape: shapeOther},
+
+		// Login
+		{method: "POST", path: "/api/v1/login", body: `{"email": "steven@gmail.com", "password": "password"}`, statusCode: 200, contentType: jsonType, shape: shapeString},
+		{method: "POST", path: "/api/v1/login", body: `{"email": "steven@gmail.com", "password": "wrong"}`, statusCode: 401, shape: shapeProblem, headers: []string{"WWW-Authenticate"}},
+		{method: "POST", path: "/api/v1/login", body: `{"email": "nobody@gmail.com", "password": "password"}`, statusCode: 401, shape: shapeProblem},
+		{method: "POST", path: "/api/v1/login", body: `{"email": "", "password": ""}`, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "POST", path: "/api/v1/login", body: `not json`, statusCode: 422, shape: shapeProblem},
+
+		// Users
+		{method: "POST", path: "/api/v1/users", body: `{"nickname": "Pet", "email": "pet@gmail.com", "password": "password"}`, statusCode: 201, contentType: jsonType, shape: shapeObject, headers: []string{"Location"}, keys: []string{"id", "nickname", "email"}},
+		{method: "POST", path: "/api/v1/users", body: `{"nickname": "Other Pet", "email": "pet@gmail.com", "password": "password"}`, statusCode: 409, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "POST", path: "/api/v1/users", body: `{}`, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "GET", path: "/api/v1/users", statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "GET", path: me, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"id", "nickname"}},
+		{method: "GET", path: "/api/v1/users/999", statusCode: 404, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/users/first", statusCode: 400, shape: shapeProblem},
+		{method: "PUT", path: me, body: `{"nickname": "Steven", "email": "steven@gmail.com", "password": "password"}`, statusCode: 401, shape: shapeProblem, headers: []string{"WWW-Authenticate"}},
+		{method: "PUT", path: me, body: `{"nickname": "Steven", "email": "steven@gmail.com", "password": "password"}`, token: "Bearer wrong", statusCode: 401, shape: shapeProblem},
+		{method: "PUT", path: other, body: `{"nickname": "Magu", "email": "magu@gmail.com", "password": "password"}`, token: bearer, statusCode: 403, shape: shapeProblem},
+		{method: "PUT", path: me, body: `{"nickname": "", "email": "steven", "password": ""}`, token: bearer, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "PUT", path: me, body: `{"nickname": "Steven", "email": "steven@gmail.com", "password": "password"}`, token: bearer, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"id", "nickname"}},
+		{method: "DELETE", path: other, token: bearer, statusCode: 403, shape: shapeProblem},
+
+		// Follows
+		{method: "POST", path: other + "/follow", token: bearer, statusCode: 201, contentType: jsonType, shape: shapeObject, keys: []string{"follower_id", "following_id"}},
+		{method: "POST", path: other + "/follow", token: bearer, statusCode: 409, shape: shapeProblem},
+		{method: "POST", path: me + "/follow", token: bearer, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "POST", path: "/api/v1/users/999/follow", token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "POST", path: other + "/follow", statusCode: 401, shape: shapeProblem},
+		{method: "GET", path: other + "/followers", statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "GET", path: me + "/following", statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "GET", path: me + "/following?limit=abc", statusCode: 400, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/feed", token: bearer, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"posts"}},
+		{method: "GET", path: "/api/v1/feed", statusCode: 401, shape: shapeProblem},
+		{method: "DELETE", path: other + "/follow", token: bearer, statusCode: 204, shape: shapeEmpty},
+		{method: "DELETE", path: other + "/follow", token: bearer, statusCode: 404, shape: shapeProblem},
+
+		// Posts
+		{method: "POST", path: "/api/v1/posts", body: fmt.Sprintf(`{"title": "New post", "content": "New content #go", "author_id": %d}`, users[0].ID), token: bearer, statusCode: 201, contentType: jsonType, shape: shapeObject, headers: []string{"Location"}, keys: []string{"id", "slug"}},
+		{method: "POST", path: "/api/v1/posts", body: fmt.Sprintf(`{"title": "New post", "content": "Again", "author_id": %d}`, users[0].ID), token: bearer, statusCode: 409, shape: shapeProblem},
+		{method: "POST", path: "/api/v1/posts", body: fmt.Sprintf(`{"title": "Impostor", "content": "Content", "author_id": %d}`, users[1].ID), token: bearer, statusCode: 403, shape: shapeProblem},
+		{method: "POST", path: "/api/v1/posts", body: `{}`, token: bearer, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "POST", path: "/api/v1/posts", body: `{}`, statusCode: 401, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/posts", statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "GET", path: mine, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"id", "title", "author"}},
+		{method: "GET", path: mine + "?format=pdf", statusCode: 400, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/posts/999", statusCode: 404, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/posts/first", statusCode: 400, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/posts/by-slug/" + posts[0].Slug, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"id", "slug"}},
+		{method: "GET", path: "/api/v1/posts/by-slug/nothing", statusCode: 404, shape: shapeProblem},
+		{method: "PUT", path: mine, body: fmt.Sprintf(`{"title": "Title 1", "content": "Updated", "author_id": %d}`, users[0].ID), token: bearer, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"id", "content"}},
+		{method: "PUT", path: mine, body: fmt.Sprintf(`{"title": "Title 1", "content": "Updated", "author_id": %d}`, users[1].ID), token: bearer, statusCode: 403, shape: shapeProblem},
+		{method: "PUT", path: theirs, body: fmt.Sprintf(`{"title": "Mine now", "content": "Updated", "author_id": %d}`, users[0].ID), token: bearer, statusCode: 403, shape: shapeProblem},
+		{method: "PUT", path: "/api/v1/posts/999", body: `{}`, token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "PUT", path: mine, body: `{}`, statusCode: 401, shape: shapeProblem},
+
+		// Reactions
+		{method: "GET", path: mine + "/reactions", statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"post_id", "reactions", "my_reactions"}},
+		{method: "GET", path: "/api/v1/posts/999/reactions", statusCode: 404, shape: shapeProblem},
+		{method: "POST", path: mine + "/reactions", body: `{"type": "like"}`, token: bearer, statusCode: 201, contentType: jsonType, shape: shapeObject, keys: []string{"type"}},
+		{method: "POST", path: mine + "/reactions", body: `{"type": "like"}`, token: bearer, statusCode: 409, shape: shapeProblem},
+		{method: "POST", path: mine + "/reactions", body: `{"type": "shrug"}`, token: bearer, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "POST", path: "/api/v1/posts/999/reactions", body: `{"type": "like"}`, token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "DELETE", path: mine + "/reactions/shrug", token: bearer, statusCode: 400, shape: shapeProblem},
+		{method: "DELETE", path: mine + "/reactions/like", token: bearer, statusCode: 204, shape: shapeEmpty},
+		{method: "DELETE", path: mine + "/reactions/like", token: bearer, statusCode: 404, shape: shapeProblem},
+
+		// Reading lists and bookmarks
+		{method: "POST", path: "/api/v1/me/reading-lists", body: `{"name": "Later"}`, token: bearer, statusCode: 201, contentType: jsonType, shape: shapeObject, keys: []string{"id", "name"}},
+		{method: "POST", path: "/api/v1/me/reading-lists", body: `{"name": "Later"}`, token: bearer, statusCode: 409, shape: shapeProblem},
+		{method: "POST", path: "/api/v1/me/reading-lists", body: `{}`, token: bearer, statusCode: 422, shape: shapeProblem, keys: []string{"errors"}},
+		{method: "GET", path: "/api/v1/me/reading-lists", token: bearer, statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "POST", path: mine + "/bookmark", token: bearer, statusCode: 201, contentType: jsonType, shape: shapeObject, keys: []string{"post_id"}},
+		{method: "POST", path: mine + "/bookmark", body: `{"reading_list_id": 1}`, token: bearer, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"reading_list_id"}},
+		{method: "POST", path: mine + "/bookmark", body: `{"reading_list_id": 999}`, token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "POST", path: "/api/v1/posts/999/bookmark", token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/me/bookmarks", token: bearer, statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "GET", path: "/api/v1/me/bookmarks?list=first", token: bearer, statusCode: 400, shape: shapeProblem},
+		{method: "DELETE", path: mine + "/bookmark", token: bearer, statusCode: 204, shape: shapeEmpty},
+		{method: "DELETE", path: mine + "/bookmark", token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "DELETE", path: "/api/v1/me/reading-lists/1", token: bearer, statusCode: 204, shape: shapeEmpty},
+		{method: "DELETE", path: "/api/v1/me/reading-lists/1", token: bearer, statusCode: 404, shape: shapeProblem},
+
+		// Tags, feeds and sitemaps
+		{method: "GET", path: "/api/v1/tags/go/posts", statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "GET", path: "/feeds/posts.rss", statusCode: 200, contentType: rssType, shape: shapeOther, headers: []string{"ETag", "Last-Modified"}},
+		{method: "GET", path: "/feeds/users/999/posts.rss", statusCode: 404, shape: shapeProblem},
+		{method: "GET", path: "/feeds/tags/nothing/posts.rss", statusCode: 404, shape: shapeProblem},
+		{method: "GET", path: "/sitemap.xml", statusCode: 200, contentType: xmlType, shape: shapeOther},
+		{method: "GET", path: "/sitemaps/tags-1.xml", statusCode: 404, shape: shapeProblem},
+
+		// GraphQL
+		{method: "POST", path: "/graphql", body: `{"query": "{ posts { id title author { nickname } } }"}`, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"data"}},
+		{method: "POST", path: "/graphql", body: `{"query": "{ me { id } }"}`, token: bearer, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"data"}},
+		{method: "POST", path: "/graphql", body: `{"query": "mutation { deletePost(id: 999) }"}`, token: bearer, statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"data", "errors"}},
+		{method: "POST", path: "/graphql", body: `{"query": "{ nothing }"}`, statusCode: 400, contentType: jsonType, shape: shapeObject, keys: []string{"errors"}},
+		{method: "POST", path: "/graphql", body: `{"query": "{ me { id } }"}`, token: "Bearer wrong", statusCode: 401, shape: shapeProblem},
+		{method: "POST", path: "/graphql", body: `not json`, statusCode: 422, shape: shapeProblem},
+		{method: "GET", path: "/graphql?query=%7B%20posts%20%7B%20id%20%7D%20%7D", statusCode: 200, contentType: jsonType, shape: shapeObject, keys: []string{"data"}},
+		{method: "GET", path: "/graphql?query=mutation%20%7B%20deletePost(id%3A%20999)%20%7D", token: bearer, statusCode: 405, shape: shapeProblem},
+
+		// Notifications
+		{method: "GET", path: "/api/v1/me/notifications", token: bearer, statusCode: 200, contentType: jsonType, shape: shapeArray},
+		{method: "PUT", path: "/api/v1/me/notifications/999/read", token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "GET", path: "/api/v1/me/notifications", statusCode: 401, shape: shapeProblem},
+
+		// Unknown routes and methods
+		{method: "GET", path: "/nothing", statusCode: 404, shape: shapeProblem},
+		{method: "PATCH", path: "/api/v1/posts", statusCode: 405, shape: shapeProblem},
+
+		// Deletions come last
+		{method: "DELETE", path: theirs, token: bearer, statusCode: 403, shape: shapeProblem},
+		{method: "DELETE", path: "/api/v1/posts/999", token: bearer, statusCode: 404, shape: shapeProblem},
+		{method: "DELETE", path: mine, statusCode: 401, shape: shapeProblem},
+		{method: "DELETE", path: mine, token: bearer, statusCode: 204, shape: shapeEmpty, headers: []string{"Entity"}},
+		{method: "DELETE", path: me, token: bearer, statusCode: 204, shape: shapeEmpty, headers: []string{"Entity"}},
+	}
+
+	for _, v := range samples {
+		name := v.method + " " + v.path
+		req, _ := http.NewRequest(v.method, v.path, bytes.NewBufferString(v.body))
+		if v.token != "" {
+			req.Header.Set("Authorization", v.token)
+		}
+		rr := httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+
+		if rr.Code != v.statusCode {
+			t.Errorf("%s: status %d, want %d: %s", name, rr.Code, v.statusCode, rr.Body.String())
+			continue
+		}
+		if rr.Header().Get("X-Request-ID") == "" {
+			t.Errorf("%s: no request ID", name)
+		}
+		for _, header := range v.headers {
+			if rr.Header().Get(header) == "" {
+				t.Errorf("%s: no %s header", name, header)
+			}
+		}
+		contentType := v.contentType
+		if v.shape == shapeProblem {
+			contentType = responses.ProblemContentType
+		}
+		if !strings.HasPrefix(rr.Header().Get("Content-Type"), contentType) {
+			t.Errorf("%s: content type %q, want %q", name, rr.Header().Get("Content-Type"), contentType)
+		}
+		checkShape(t, name, rr, v)
+		spec.checkResponse(t, name, req, rr)
+	}
+}
+
+func checkShape(t *testing.T, name string, rr *httptest.ResponseRecorder, v contract) {
+	switch v.shape {
+	case shapeOther:
+		assert.NotEqual(t, rr.Body.Len(), 0)
+		return
+	case shapeEmpty:
+		assert.Equal(t, rr.Body.Len(), 0)
+		assert.Equal(t, rr.Header().Get("Content-Type"), "")
+		return
+	}
+
+	var body interface{}
+	err := json.Unmarshal(rr.Body.Bytes(), &body)
+	if err != nil {
+		t.Errorf("%s: cannot convert to json: %v", name, err)
+		return
+	}
+	switch v.shape {
+	case shapeString:
+		_, ok := body.(string)
+		assert.Equal(t, ok, true)
+	case shapeArray:
+		_, ok := body.([]interface{})
+		assert.Equal(t, ok, true)
+	case shapeObject, shapeProblem:
+		object, ok := body.(map[string]interface{})
+		if !ok {
+			t.Errorf("%s: body is not an object: %s", name, rr.Body.String())
+			return
+		}
+		keys := v.keys
+		if v.shape == shapeProblem {
+			keys = append(keys, "type", "title", "status", "detail", "request_id")
+			assert.Equal(t, object["status"], float64(v.statusCode))
+			assert.Equal(t, object["request_id"], rr.Header().Get("X-Request-ID"))
+		}
+		for _, key := range keys {
+			if _, ok := object[key]; !ok {
+				t.Errorf("%s: no %q in %s", name, key, rr.Body.String())
+			}
+		}
+	}
+}
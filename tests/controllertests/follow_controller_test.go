@@ -0,0 +1,282 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestFollowUser(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		id           string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			id:         strconv.Itoa(int(users[1].ID)),
+			tokenGiven: tokenString,
+			statusCode: 201,
+		}, {
+			// following twice
+			id:           strconv.Itoa(int(users[1].ID)),
+			tokenGiven:   tokenString,
+			statusCode:   409,
+			errorMessage: "Already Following",
+		}, {
+			id:           strconv.Itoa(int(users[0].ID)),
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "Cannot Follow Yourself",
+		}, {
+			id:           "100",
+			tokenGiven:   tokenString,
+			statusCode:   404,
+			errorMessage: "User not found",
+		}, {
+			id:           strconv.Itoa(int(users[1].ID)),
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		}, {
+			id:         "unknown",
+			tokenGiven: tokenString,
+			statusCode: 400,
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("POST", "/users/follow", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.FollowUser)
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 201 {
+			assert.Equal(t, responseMap["follower_id"], float64(users[0].ID))
+			assert.Equal(t, responseMap["following_id"], float64(users[1].ID))
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
+		}
+	}
+}
+
+func TestUnfollowUser(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndFollows()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		id         string
+		tokenGiven string
+		statusCode int
+	}{
+		{
+			id:         strconv.Itoa(int(users[1].ID)),
+			tokenGiven: tokenString,
+			statusCode: 204,
+		}, {
+			// not following anymore
+			id:         strconv.Itoa(int(users[1].ID)),
+			tokenGiven: tokenString,
+			statusCode: 404,
+		}, {
+			id:         strconv.Itoa(int(users[1].ID)),
+			tokenGiven: "Wrong token",
+			statusCode: 401,
+		},
+	}
+
+	for _, v := range samples {
+		req, _ := http.NewRequest("DELETE", "/users/follow", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.UnfollowUser)
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+}
+
+func TestGetFollowersAndFollowing(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndFollows()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		handler    http.HandlerFunc
+		id         string
+		query      string
+		statusCode int
+		length     int
+	}{
+		{
+			handler:    server.GetFollowers,
+			id:         strconv.Itoa(int(users[1].ID)),
+			statusCode: 200,
+			length:     1,
+		}, {
+			handler:    server.GetFollowers,
+			id:         strconv.Itoa(int(users[0].ID)),
+			statusCode: 200,
+			length:     0,
+		}, {
+			handler:    server.GetFollowing,
+			id:         strconv.Itoa(int(users[0].ID)),
+			statusCode: 200,
+			length:     1,
+		}, {
+			handler:    server.GetFollowing,
+			id:         strconv.Itoa(int(users[0].ID)),
+			query:      "?offset=1",
+			statusCode: 200,
+			length:     0,
+		}, {
+			handler:    server.GetFollowing,
+			id:         strconv.Itoa(int(users[0].ID)),
+			query:      "?limit=abc",
+			statusCode: 400,
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/users/follow"+v.query, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		v.handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			var users []models.User
+			err = json.Unmarshal([]byte(rr.Body.String()), &users)
+			if err != nil {
+				t.Errorf("Could not unmarshal: %v\n", err)
+			}
+			assert.Equal(t, len(users), v.length)
+		}
+	}
+}
+
+func TestGetFeed(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndFollows()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// one more post by the followed user so that the feed spans two pages
+	post := models.Post{
+		Title:    "Title 3",
+		Content:  "Hello world 3",
+		AuthorID: users[1].ID,
+	}
+	err = server.DB.Model(&models.Post{}).Create(&post).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	type feedResponse struct {
+		Posts      []models.Post `json:"posts"`
+		NextCursor string        `json:"next_cursor"`
+	}
+
+	req, _ := http.NewRequest("GET", "/feed?limit=1", nil)
+	req.Header.Set("Authorization", tokenString)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetFeed).ServeHTTP(rr, req)
+
+	firstPage := feedResponse{}
+	err = json.Unmarshal([]byte(rr.Body.String()), &firstPage)
+	if err != nil {
+		t.Errorf("Could not unmarshal: %v\n", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, len(firstPage.Posts), 1)
+	assert.Equal(t, firstPage.Posts[0].ID, post.ID)
+	assert.NotEqual(t, firstPage.NextCursor, "")
+
+	req, _ = http.NewRequest("GET", "/feed?limit=1&cursor="+firstPage.NextCursor, nil)
+	req.Header.Set("Authorization", tokenString)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetFeed).ServeHTTP(rr, req)
+
+	secondPage := feedResponse{}
+	err = json.Unmarshal([]byte(rr.Body.String()), &secondPage)
+	if err != nil {
+		t.Errorf("Could not unmarshal: %v\n", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, len(secondPage.Posts), 1)
+	assert.Equal(t, secondPage.Posts[0].AuthorID, users[1].ID)
+	assert.Equal(t, secondPage.NextCursor, "")
+
+	req, _ = http.NewRequest("GET", "/feed?cursor=garbage", nil)
+	req.Header.Set("Authorization", tokenString)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetFeed).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
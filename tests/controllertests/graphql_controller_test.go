@@ -0,0 +1,340 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type graphqlResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+// graphql posts the query with the token, and decodes the result
+func graphql(t *testing.T, query string, token string) (int, graphqlResult) {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GraphQL).ServeHTTP(rr, req)
+
+	result := graphqlResult{}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "application/problem+json") {
+		err := json.Unmarshal(rr.Body.Bytes(), &result)
+		if err != nil {
+			t.Errorf("Could not unmarshal %q: %v", rr.Body.String(), err)
+		}
+	}
+	return rr.Code, result
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	draft := models.Post{Title: "Draft", Content: "Not yet", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	draft.Prepare()
+	_, err = draft.SavePost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples := []struct {
+		query string
+		token string
+		want  string
+	}{
+		{
+			query: `{ posts { title author { nickname } } }`,
+			want:  `{"posts":[{"author":{"nickname":"Magu Frank"},"title":"Title 2"},{"author":{"nickname":"Steven victor"},"title":"Title 1"}]}`,
+		},
+		{
+			query: `{ posts(limit: 1) { title } }`,
+			want:  `{"posts":[{"title":"Title 2"}]}`,
+		},
+		{
+			query: fmt.Sprintf(`{ post(slug: %q) { id reactionCount reactions { like } } }`, posts[0].Slug),
+			want:  fmt.Sprintf(`{"post":{"id":"%d","reactionCount":0,"reactions":{"like":0}}}`, posts[0].ID),
+		},
+		{
+			query: fmt.Sprintf(`{ user(id: %d) { nickname posts { title } } }`, users[0].ID),
+			want:  `{"user":{"nickname":"Steven victor","posts":[{"title":"Title 1"}]}}`,
+		},
+		{
+			query: fmt.Sprintf(`{ post(id: %d) { title } }`, draft.ID),
+			want:  `{"post":null}`,
+		},
+		{
+			query: fmt.Sprintf(`{ post(id: %d) { title } }`, draft.ID),
+			token: token,
+			want:  `{"post":{"title":"Draft"}}`,
+		},
+		{
+			query: `{ me { nickname } }`,
+			token: token,
+			want:  `{"me":{"nickname":"Steven victor"}}`,
+		},
+		{
+			query: `{ me { nickname } user(id: 999) { nickname } post(slug: "nothing") { title } }`,
+			want:  `{"me":null,"post":null,"user":null}`,
+		},
+	}
+	for _, v := range samples {
+		statusCode, result := graphql(t, v.query, v.token)
+		assert.Equal(t, statusCode, http.StatusOK)
+		assert.Equal(t, len(result.Errors), 0)
+		data, _ := json.Marshal(result.Data)
+		assert.Equal(t, string(data), v.want)
+	}
+}
+
+// TestGraphQLBatchesQueries checks the number of SQL queries of a request does not
+// grow with the number of users and posts it returns
+func TestGraphQLBatchesQueries(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	queries := 0
+	server.DB.Callback().Query().After("gorm:query").Register("graphqltests:count", func(*gorm.Scope) {
+		queries++
+	})
+	defer server.DB.Callback().Query().Remove("graphqltests:count")
+
+	count := func(query string) int {
+		queries = 0
+		statusCode, result := graphql(t, query, "")
+		assert.Equal(t, statusCode, http.StatusOK)
+		assert.Equal(t, len(result.Errors), 0)
+		return queries
+	}
+	samples := []string{
+		`{ posts { title author { nickname } } }`,
+		`{ users { nickname posts { title author { nickname } } } }`,
+	}
+	before := make([]int, len(samples))
+	for i, query := range samples {
+		before[i] = count(query)
+	}
+	for i := 0; i < 5; i++ {
+		user := models.User{Nickname: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@gmail.com", i), Password: "password"}
+		err = server.DB.Create(&user).Error
+		if err != nil {
+			log.Fatal(err)
+		}
+		for j := 0; j < 2; j++ {
+			post := models.Post{Title: fmt.Sprintf("Post %d.%d", i, j), Content: "Content", AuthorID: user.ID}
+			post.Prepare()
+			_, err = post.SavePost(server.DB)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	for i, query := range samples {
+		assert.Equal(t, count(query), before[i])
+	}
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples := []struct {
+		query string
+		token string
+		want  string
+		// status is the status in the extensions of the error, 0 without an error
+		status float64
+		fields bool
+	}{
+		{
+			query: `mutation { createPost(input: {title: "Created", content: "Some #go"}) { title tags author { nickname } } }`,
+			token: token,
+			want:  `{"createPost":{"author":{"nickname":"Steven victor"},"tags":["go"],"title":"Created"}}`,
+		},
+		{
+			query:  `mutation { createPost(input: {title: "Created", content: "Again"}) { title } }`,
+			token:  token,
+			want:   `null`,
+			status: 409,
+			fields: true,
+		},
+		{
+			query:  `mutation { createPost(input: {title: "", content: ""}) { title } }`,
+			token:  token,
+			want:   `null`,
+			status: 422,
+			fields: true,
+		},
+		{
+			query:  `mutation { createPost(input: {title: "Anonymous", content: "Content"}) { title } }`,
+			want:   `null`,
+			status: 401,
+		},
+		{
+			query: fmt.Sprintf(`mutation { updatePost(id: %d, input: {title: "Title 1", content: "Updated"}) { content } }`, posts[0].ID),
+			token: token,
+			want:  `{"updatePost":{"content":"Updated"}}`,
+		},
+		{
+			query:  fmt.Sprintf(`mutation { updatePost(id: %d, input: {title: "Mine now", content: "Updated"}) { content } }`, posts[1].ID),
+			token:  token,
+			want:   `null`,
+			status: 403,
+		},
+		{
+			query:  `mutation { deletePost(id: 999) }`,
+			token:  token,
+			want:   `null`,
+			status: 404,
+		},
+		{
+			query: fmt.Sprintf(`mutation { deletePost(id: %d) }`, posts[0].ID),
+			token: token,
+			want:  fmt.Sprintf(`{"deletePost":"%d"}`, posts[0].ID),
+		},
+		{
+			query:  `mutation { login(email: "steven@gmail.com", password: "wrong") }`,
+			want:   `null`,
+			status: 401,
+		},
+	}
+	for _, v := range samples {
+		statusCode, result := graphql(t, v.query, v.token)
+		assert.Equal(t, statusCode, http.StatusOK)
+		data, _ := json.Marshal(result.Data)
+		assert.Equal(t, string(data), v.want)
+		if v.status == 0 {
+			assert.Equal(t, len(result.Errors), 0)
+			continue
+		}
+		if len(result.Errors) != 1 {
+			t.Errorf("%s: errors %v, want one", v.query, result.Errors)
+			continue
+		}
+		assert.Equal(t, result.Errors[0].Extensions["status"], v.status)
+		_, fields := result.Errors[0].Extensions["errors"]
+		assert.Equal(t, fields, v.fields)
+	}
+
+	statusCode, result := graphql(t, `mutation { login(email: "steven@gmail.com", password: "password") }`, "")
+	assert.Equal(t, statusCode, http.StatusOK)
+	login, _ := result.Data["login"].(string)
+	statusCode, result = graphql(t, `{ me { nickname } }`, login)
+	assert.Equal(t, statusCode, http.StatusOK)
+	assert.Equal(t, result.Data["me"], map[string]interface{}{"nickname": "Steven victor"})
+}
+
+func TestGraphQLRejectedRequests(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, _, err = seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	samples := []struct {
+		query      string
+		token      string
+		statusCode int
+		message    string
+	}{
+		{
+			query:      `{ users { posts { author { posts { author { posts { author { posts { id } } } } } } } } }`,
+			statusCode: 400,
+			message:    "Query Too Deep",
+		},
+		{
+			query:      `{ users { posts(limit: 100) { author { nickname } } } }`,
+			statusCode: 400,
+			message:    "Query Too Complex",
+		},
+		{
+			query:      `query Many { users { ...userPosts } } fragment userPosts on User { posts(limit: 100) { title author { nickname } } }`,
+			statusCode: 400,
+			message:    "Query Too Complex",
+		},
+		{
+			query:      `{ users { posts(limit: 10) { author { nickname } } } }`,
+			statusCode: 200,
+		},
+		{
+			query:      `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`,
+			statusCode: 200,
+		},
+		{
+			query:      `{ posts { nothing } }`,
+			statusCode: 400,
+			message:    "Cannot query field",
+		},
+		{
+			query:      `{ posts {`,
+			statusCode: 400,
+			message:    "Syntax Error",
+		},
+		{
+			query:      `{ posts(limit: 1000) { id } }`,
+			statusCode: 200,
+			message:    "Invalid Limit",
+		},
+		{
+			query:      `{ me { id } }`,
+			token:      "wrong",
+			statusCode: 401,
+		},
+	}
+	for _, v := range samples {
+		statusCode, result := graphql(t, v.query, v.token)
+		assert.Equal(t, statusCode, v.statusCode)
+		if v.message == "" {
+			assert.Equal(t, len(result.Errors), 0)
+			continue
+		}
+		if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Message, v.message) {
+			t.Errorf("%s: errors %v, want %q", v.query, result.Errors, v.message)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/graphql?query="+strings.ReplaceAll(`mutation { deletePost(id: 1) }`, " ", "%20"), nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GraphQL).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusMethodNotAllowed)
+}
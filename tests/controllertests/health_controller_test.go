@@ -0,0 +1,186 @@
+package controllertests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/controllers"
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/api/workers"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type readinessResponse struct {
+	Status string `json:"status"`
+	Checks map[string]struct {
+		Status  string           `json:"status"`
+		Error   string           `json:"error"`
+		Pending *int             `json:"pending"`
+		Workers []workers.Health `json:"workers"`
+	} `json:"checks"`
+}
+
+func TestHealthz(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.Healthz).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 200)
+	assert.Equal(t, rr.Body.String(), "{\"status\":\"ok\"}\n")
+}
+
+// startWorker runs a worker until its first run is over, then leaves it running
+// or stopped
+func startWorker(job workers.Job, keepRunning bool) *workers.Group {
+	group := workers.NewGroup(&workers.Worker{Name: "job", Interval: time.Hour, Job: job})
+	group.Start()
+	for group.Health()[0].LastRun == nil {
+		time.Sleep(time.Millisecond)
+	}
+	if !keepRunning {
+		group.Stop(context.Background())
+	}
+	return group
+}
+
+func TestReadyz(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("Cannot prune") }
+
+	running := startWorker(ok, true)
+	defer running.Stop(context.Background())
+	draining := &controllers.Server{DB: server.DB, Workers: running}
+	draining.Drain()
+
+	samples := []struct {
+		server         *controllers.Server
+		pendingSteps   int
+		statusCode     int
+		failingChecks  []string
+		degradedChecks []string
+		workersChecked bool
+	}{
+		{
+			server:     &controllers.Server{DB: server.DB},
+			statusCode: 200,
+		}, {
+			server:         &controllers.Server{DB: server.DB, Workers: running},
+			statusCode:     200,
+			workersChecked: true,
+		}, {
+			server:        &controllers.Server{DB: server.DB},
+			pendingSteps:  1,
+			statusCode:    503,
+			failingChecks: []string{"migrations"},
+		}, {
+			server:         draining,
+			statusCode:     503,
+			failingChecks:  []string{"shutdown"},
+			workersChecked: true,
+		}, {
+			server:         &controllers.Server{DB: server.DB, Workers: startWorker(failing, true)},
+			statusCode:     200,
+			degradedChecks: []string{"workers"},
+			workersChecked: true,
+		}, {
+			server:         &controllers.Server{DB: server.DB, Workers: startWorker(ok, false)},
+			statusCode:     503,
+			failingChecks:  []string{"workers"},
+			workersChecked: true,
+		},
+	}
+
+	for _, v := range samples {
+		migrator, err := migrations.New(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = migrator.Down(v.pendingSteps)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(v.server.Readyz).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+		assert.Equal(t, rr.Header().Get("Cache-Control"), "no-store")
+
+		_, err = migrator.Up()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		result := readinessResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &result)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+			continue
+		}
+		if v.statusCode == 200 {
+			assert.Equal(t, result.Status, "ok")
+			assert.Equal(t, *result.Checks["migrations"].Pending, 0)
+		} else {
+			assert.Equal(t, result.Status, "unavailable")
+		}
+		assert.Equal(t, result.Checks["database"].Status, "ok")
+		_, checked := result.Checks["workers"]
+		assert.Equal(t, checked, v.workersChecked)
+		for name, c := range result.Checks {
+			status := "ok"
+			for _, f := range v.failingChecks {
+				if f == name {
+					status = "failing"
+				}
+			}
+			for _, d := range v.degradedChecks {
+				if d == name {
+					status = "degraded"
+				}
+			}
+			assert.Equal(t, c.Status, status)
+		}
+		if len(v.degradedChecks) > 0 {
+			assert.Equal(t, result.Checks["workers"].Workers[0].LastError, "Cannot prune")
+		}
+	}
+
+	// The probe reports a database never migrated, it does not create the table
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = migrator.Down(len(migrator.Migrations()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = server.DB.DropTable("schema_migrations").Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc((&controllers.Server{DB: server.DB}).Readyz).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 503)
+	assert.Equal(t, server.DB.HasTable("schema_migrations"), false)
+	result := readinessResponse{}
+	err = json.Unmarshal(rr.Body.Bytes(), &result)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, result.Checks["migrations"].Status, "failing")
+
+	err = refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,13 +3,13 @@ package controllertests
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/planutim/postgres-copy/api/apperrors"
 	"gopkg.in/go-playground/assert.v1"
 )
 
@@ -36,19 +36,20 @@ func TestSignIn(t *testing.T) {
 		{
 			email:        user.Email,
 			password:     "Wrong password",
-			errorMessage: "crypto/bcrypt: hashedPassword is not the hash of the given password",
+			errorMessage: "Incorrect Password",
 		},
 		{
 			email:        "Wrong email",
 			password:     "password",
-			errorMessage: "record not found",
+			errorMessage: "Incorrect Details",
 		},
 	}
 
 	for _, v := range samples {
 		token, err := server.SignIn(v.email, v.password)
 		if err != nil {
-			assert.Equal(t, err, errors.New(v.errorMessage))
+			assert.Equal(t, err.Error(), v.errorMessage)
+			assert.Equal(t, apperrors.KindOf(err), apperrors.Unauthorized)
 		} else {
 			assert.NotEqual(t, token, "")
 		}
@@ -77,12 +78,12 @@ func TestLogin(t *testing.T) {
 		},
 		{
 			inputJSON:    `{"email": "pet@gmail.com", "password": "wrong password"}`,
-			statusCode:   422,
+			statusCode:   401,
 			errorMessage: "Incorrect Password",
 		},
 		{
 			inputJSON:    `{"email": "frank@gmail.com", "password": "password"}`,
-			statusCode:   422,
+			statusCode:   401,
 			errorMessage: "Incorrect Details",
 		},
 		{
@@ -122,13 +123,13 @@ func TestLogin(t *testing.T) {
 			assert.NotEqual(t, rr.Body.String(), "")
 		}
 
-		if v.statusCode == 422 && v.errorMessage != "" {
+		if (v.statusCode == 401 || v.statusCode == 422) && v.errorMessage != "" {
 			responseMap := make(map[string]interface{})
 			err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
 			if err != nil {
 				t.Errorf("Cannot convert to json: %v", err)
 			}
-			assert.Equal(t, responseMap["error"], v.errorMessage)
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
 		}
 	}
 }
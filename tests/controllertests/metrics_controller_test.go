@@ -0,0 +1,134 @@
+package controllertests
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/config"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/metrics"
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func scrape() string {
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, req)
+	return rr.Body.String()
+}
+
+func TestHTTPMetrics(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.Use(metrics.Middleware)
+	router.NotFoundHandler = metrics.Middleware(http.NotFoundHandler())
+	router.HandleFunc("/metrics-test/posts/{id}", server.GetPost).Methods("GET")
+
+	paths := []string{
+		fmt.Sprintf("/metrics-test/posts/%d", posts[0].ID),
+		fmt.Sprintf("/metrics-test/posts/%d", posts[1].ID),
+		"/metrics-test/posts/first",
+		"/metrics-test/nothing",
+	}
+	for _, path := range paths {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	body := scrape()
+	samples := []string{
+		`http_requests_total{method="GET",route="/metrics-test/posts/{id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/metrics-test/posts/{id}",status="400"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"}`,
+		`http_request_duration_seconds_count{method="GET",route="/metrics-test/posts/{id}",status="200"} 2`,
+	}
+	for _, sample := range samples {
+		assert.Equal(t, strings.Contains(body, sample), true)
+	}
+	assert.Equal(t, strings.Contains(body, fmt.Sprintf("/metrics-test/posts/%d", posts[0].ID)), false)
+}
+
+func TestDatabaseMetrics(t *testing.T) {
+	db, err := database.Open(config.DBConfig{Driver: "sqlite3", Name: ":memory:"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	err = metrics.InstrumentDB(db, "metrics_test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = migrator.Up()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user := models.User{Nickname: "Pet", Email: "pet@gmail.com", Password: "password"}
+	err = db.Create(&user).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Not finding a record is not an error, a missing table is
+	db.Where("id = ?", 12345).Take(&models.User{})
+	err = db.Table("nothings").Find(&[]models.User{}).Error
+	assert.NotEqual(t, err, nil)
+
+	body := scrape()
+	samples := []string{
+		`db_query_duration_seconds_count{operation="create",table="users"} 1`,
+		`db_query_duration_seconds_count{operation="query",table="users"} 1`,
+		`db_query_errors_total{operation="query",table="nothings"} 1`,
+		`go_sql_max_open_connections{db_name="metrics_test"} 1`,
+	}
+	for _, sample := range samples {
+		assert.Equal(t, strings.Contains(body, sample), true)
+	}
+	assert.Equal(t, strings.Contains(body, `db_query_errors_total{operation="query",table="users"}`), false)
+}
+
+func TestBusinessMetrics(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	created := testutil.ToFloat64(metrics.UsersCreated)
+	failures := testutil.ToFloat64(metrics.LoginFailures)
+
+	requests := []struct {
+		handler   http.HandlerFunc
+		inputJSON string
+	}{
+		{server.CreateUser, `{"nickname":"Pet", "email": "pet@gmail.com", "password": "password"}`},
+		{server.CreateUser, `{"nickname":"Pet", "email": "pet@gmail.com", "password": "password"}`},
+		{server.Login, `{"email": "pet@gmail.com", "password": "password"}`},
+		{server.Login, `{"email": "pet@gmail.com", "password": "wrong password"}`},
+		{server.Login, `{"email": "", "password": "password"}`},
+	}
+	for _, v := range requests {
+		req, _ := http.NewRequest("POST", "/", bytes.NewBufferString(v.inputJSON))
+		v.handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, testutil.ToFloat64(metrics.UsersCreated)-created, float64(1))
+	assert.Equal(t, testutil.ToFloat64(metrics.LoginFailures)-failures, float64(2))
+}
@@ -0,0 +1,117 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestMentionNotifications(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	mentioned := models.User{
+		Nickname: "kenny",
+		Email:    "kenny@gmail.com",
+		Password: "password",
+	}
+	err = server.DB.Model(&models.User{}).Create(&mentioned).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authorToken, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	mentionedToken, err := server.SignIn(mentioned.Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	inputJSON := fmt.Sprintf(`{"title": "Shout out", "content": "Thanks @kenny", "author_id": %d}`, users[0].ID)
+	req, _ := http.NewRequest("POST", "/posts", bytes.NewBufferString(inputJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", authorToken))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreatePost).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, responseMap["content_html"], fmt.Sprintf("<p>Thanks <a href=\"/api/v1/users/%d\" class=\"mention\" rel=\"nofollow\">@kenny</a></p>\n", mentioned.ID))
+
+	req, _ = http.NewRequest("GET", "/me/notifications?unread=true", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", mentionedToken))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetMyNotifications).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	var notifications []models.Notification
+	err = json.Unmarshal([]byte(rr.Body.String()), &notifications)
+	if err != nil {
+		t.Errorf("Could not unmarshal: %v\n", err)
+	}
+	assert.Equal(t, len(notifications), 1)
+	assert.Equal(t, notifications[0].ActorID, users[0].ID)
+
+	samples := []struct {
+		id         string
+		tokenGiven string
+		statusCode int
+	}{
+		{
+			// somebody else's notification
+			id:         strconv.Itoa(int(notifications[0].ID)),
+			tokenGiven: fmt.Sprintf("Bearer %v", authorToken),
+			statusCode: 404,
+		}, {
+			id:         strconv.Itoa(int(notifications[0].ID)),
+			tokenGiven: fmt.Sprintf("Bearer %v", mentionedToken),
+			statusCode: 200,
+		}, {
+			id:         "unknown",
+			tokenGiven: fmt.Sprintf("Bearer %v", mentionedToken),
+			statusCode: 400,
+		}, {
+			id:         strconv.Itoa(int(notifications[0].ID)),
+			tokenGiven: "",
+			statusCode: 401,
+		},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("PUT", "/me/notifications/read", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.ReadNotification).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+
+	req, _ = http.NewRequest("GET", "/me/notifications?unread=true", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", mentionedToken))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetMyNotifications).ServeHTTP(rr, req)
+	err = json.Unmarshal([]byte(rr.Body.String()), &notifications)
+	if err != nil {
+		t.Errorf("Could not unmarshal: %v\n", err)
+	}
+	assert.Equal(t, len(notifications), 0)
+}
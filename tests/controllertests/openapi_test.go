@@ -0,0 +1,283 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/controllers"
+	"github.com/planutim/postgres-copy/api/openapi"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// routeVariable is a variable of a route template restricted by a pattern, such as
+// {format:rss|atom|json}, OpenAPI only names it
+var routeVariable = regexp.MustCompile(`\{([^}:]+):[^}]*\}`)
+
+// operations are the methods of the paths of the document
+var operations = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// apiSpec checks requests and responses against the OpenAPI document
+type apiSpec struct {
+	document map[string]interface{}
+	compiler *jsonschema.Compiler
+}
+
+func loadSpec(t *testing.T) *apiSpec {
+	var document map[string]interface{}
+	err := json.Unmarshal(openapi.Document(), &document)
+	if err != nil {
+		t.Fatalf("cannot read the OpenAPI document: %v", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	err = compiler.AddResource("openapi.json", bytes.NewReader(openapi.Document()))
+	if err != nil {
+		t.Fatalf("cannot load the OpenAPI document: %v", err)
+	}
+	return &apiSpec{document: document, compiler: compiler}
+}
+
+// pathTemplate is the path of the document a route template stands for
+func pathTemplate(template string) string {
+	return routeVariable.ReplaceAllString(template, "{$1}")
+}
+
+// pointer escapes the tokens of a JSON pointer
+func pointer(tokens ...string) string {
+	escaped := make([]string, len(tokens))
+	for i, token := range tokens {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	return "#/" + strings.Join(escaped, "/")
+}
+
+// lookup follows a pointer from the root of the document
+func (s *apiSpec) lookup(ref string) (map[string]interface{}, bool) {
+	var node interface{} = s.document
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		node = object[token]
+	}
+	object, ok := node.(map[string]interface{})
+	return object, ok
+}
+
+// operations lists the operations of the document as "METHOD path"
+func (s *apiSpec) operations() []string {
+	documented := []string{}
+	paths, _ := s.document["paths"].(map[string]interface{})
+	for path, item := range paths {
+		for method := range item.(map[string]interface{}) {
+			for _, operation := range operations {
+				if method == operation {
+					documented = append(documented, strings.ToUpper(method)+" "+path)
+				}
+			}
+		}
+	}
+	sort.Strings(documented)
+	return documented
+}
+
+// checkResponse validates a response against the document: its status and media
+// type have to be documented for the operation of the route the request matched,
+// and a JSON body has to match the schema of the media type
+func (s *apiSpec) checkResponse(t *testing.T, name string, req *http.Request, rr *httptest.ResponseRecorder) {
+	var match mux.RouteMatch
+	if !server.Router.Match(req, &match) || match.Route == nil {
+		// Unknown routes and methods are answered by the router, not an operation
+		return
+	}
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	path := pathTemplate(template)
+	location := pointer("paths", path, strings.ToLower(req.Method), "responses", strconv.Itoa(rr.Code))
+	response, ok := s.lookup(location)
+	if !ok {
+		t.Errorf("%s: status %d of %s %s is not documented", name, rr.Code, req.Method, path)
+		return
+	}
+	if ref, ok := response["$ref"].(string); ok {
+		location = ref
+		response, ok = s.lookup(ref)
+		if !ok {
+			t.Errorf("%s: %s is not in the document", name, ref)
+			return
+		}
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if rr.Body.Len() == 0 {
+		if len(content) > 0 && rr.Code != http.StatusNotModified {
+			t.Errorf("%s: no body, %s documents one", name, location)
+		}
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+	if err != nil {
+		t.Errorf("%s: content type %q: %v", name, rr.Header().Get("Content-Type"), err)
+		return
+	}
+	if _, ok := content[mediaType]; !ok {
+		t.Errorf("%s: content type %s is not documented in %s", name, mediaType, location)
+		return
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return
+	}
+
+	schema, err := s.compiler.Compile("openapi.json" + location + pointer("content", mediaType, "schema")[1:])
+	if err != nil {
+		t.Errorf("%s: cannot compile the schema of %s: %v", name, location, err)
+		return
+	}
+	var body interface{}
+	err = json.Unmarshal(rr.Body.Bytes(), &body)
+	if err != nil {
+		t.Errorf("%s: cannot convert to json: %v", name, err)
+		return
+	}
+	err = schema.Validate(body)
+	if err != nil {
+		t.Errorf("%s: the body does not match %s: %v\n%s", name, location, err, rr.Body.String())
+	}
+}
+
+// TestOpenAPIDocumentsEveryRoute checks the document describes every route of the
+// router, and nothing else
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	spec := loadSpec(t)
+	server.InitializeRouter()
+
+	routes := []string{}
+	err := server.Router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		// The deprecated routes are the routes of the first version again
+		if route.GetName() == controllers.DeprecatedRoutes {
+			return mux.SkipRouter
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		if route.GetHandler() == nil {
+			// The route of a subrouter, its routes come next
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return fmt.Errorf("%s has no methods: %v", template, err)
+		}
+		for _, method := range methods {
+			routes = append(routes, method+" "+pathTemplate(template))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(routes)
+	assertSameOperations(t, routes, spec.operations())
+}
+
+func assertSameOperations(t *testing.T, routes, documented []string) {
+	known := map[string]bool{}
+	for _, operation := range documented {
+		known[operation] = true
+	}
+	for _, route := range routes {
+		if !known[route] {
+			t.Errorf("%s is not documented", route)
+		}
+		delete(known, route)
+	}
+	for operation := range known {
+		t.Errorf("%s is documented but has no route", operation)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	server.InitializeRouter()
+
+	samples := []struct {
+		path        string
+		contentType string
+	}{
+		{path: "/openapi.json", contentType: "application/json"},
+		{path: "/docs", contentType: "text/html; charset=utf-8"},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", v.path, nil)
+		rr := httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: status %d", v.path, rr.Code)
+			continue
+		}
+		if rr.Header().Get("Content-Type") != v.contentType {
+			t.Errorf("%s: content type %q, want %q", v.path, rr.Header().Get("Content-Type"), v.contentType)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	server.Router.ServeHTTP(rr, req)
+	var document map[string]interface{}
+	err := json.Unmarshal(rr.Body.Bytes(), &document)
+	if err != nil {
+		t.Fatalf("cannot convert to json: %v", err)
+	}
+	if document["openapi"] != "3.1.0" {
+		t.Errorf("openapi %v, want 3.1.0", document["openapi"])
+	}
+
+	// The docs page loads its assets from the API, never from another host
+	req, _ = http.NewRequest("GET", "/docs", nil)
+	rr = httptest.NewRecorder()
+	server.Router.ServeHTTP(rr, req)
+	if strings.Contains(rr.Body.String(), "://") {
+		t.Errorf("the docs page loads assets from another host: %s", rr.Body.String())
+	}
+	// Every asset of the page is embedded in the binary
+	assetLinks := regexp.MustCompile(`(?:src|href)="(/docs/assets/[^"]+)"`).FindAllStringSubmatch(rr.Body.String(), -1)
+	if len(assetLinks) != 2 {
+		t.Errorf("the docs page links %d assets, want a script and a stylesheet", len(assetLinks))
+	}
+	for _, link := range assetLinks {
+		req, _ = http.NewRequest("GET", link[1], nil)
+		rr = httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK || rr.Body.Len() == 0 {
+			t.Errorf("%s: status %d, want the asset", link[1], rr.Code)
+			continue
+		}
+		contentType := rr.Header().Get("Content-Type")
+		if !strings.HasPrefix(contentType, "text/javascript") && !strings.HasPrefix(contentType, "text/css") {
+			t.Errorf("%s: content type %q", link[1], contentType)
+		}
+	}
+	for _, name := range []string{"nothing.js", "docs.html"} {
+		req, _ = http.NewRequest("GET", "/docs/assets/"+name, nil)
+		rr = httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("/docs/assets/%s: status %d, want 404", name, rr.Code)
+		}
+	}
+}
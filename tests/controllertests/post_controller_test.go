@@ -52,7 +52,7 @@ func TestCreatePost(t *testing.T) {
 			errorMessage: "",
 		}, {
 			inputJSON:    `{"title": "The title", "content":"the content", "author_id":1}`,
-			statusCode:   500,
+			statusCode:   409,
 			tokenGiven:   tokenString,
 			errorMessage: "Title Already Taken",
 		}, {
@@ -86,9 +86,9 @@ func TestCreatePost(t *testing.T) {
 		}, {
 			// when user2 attempts to use user1 token
 			inputJSON:    `{"title":"This is the title", "content": "the content", "author_id": 2}`,
-			statusCode:   401,
+			statusCode:   403,
 			tokenGiven:   tokenString,
-			errorMessage: "Unauthorized",
+			errorMessage: "Forbidden",
 		},
 	}
 	for _, v := range samples {
@@ -115,8 +115,8 @@ func TestCreatePost(t *testing.T) {
 			assert.Equal(t, responseMap["content"], v.content)
 			assert.Equal(t, responseMap["author_id"], float64(v.author_id))
 		}
-		if v.statusCode == 401 || v.statusCode == 422 || v.statusCode == 500 && v.errorMessage != "" {
-			assert.Equal(t, responseMap["error"], v.errorMessage)
+		if v.statusCode == 401 || v.statusCode == 403 || v.statusCode == 422 || v.statusCode == 409 && v.errorMessage != "" {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
 		}
 	}
 }
@@ -279,12 +279,15 @@ func TestUpdatePost(t *testing.T) {
 			statusCode:   401,
 			errorMessage: "Unauthorized",
 		}, {
-			//title 2 belongs to post 2, and title must be unique
+			// title 2 belongs to another author's post, titles are only unique per author
 			id:           strconv.Itoa(int(AuthPostID)),
 			updateJSON:   `{"title": "Title 2", "content": "another content", "author_id": 1}`,
 			tokenGiven:   tokenString,
-			statusCode:   500,
-			errorMessage: "Title Already Taken",
+			statusCode:   200,
+			title:        "Title 2",
+			content:      "another content",
+			author_id:    AuthorPostAuthorID,
+			errorMessage: "",
 		}, {
 			id: strconv.Itoa(int(AuthPostID)),
 			updateJSON: `{"title":"", "content": "Another content", "author_id": 1
@@ -301,9 +304,9 @@ func TestUpdatePost(t *testing.T) {
 		}, {
 			id:           strconv.Itoa(int(AuthPostID)),
 			updateJSON:   `{"title": "This is another title", "content": "This is content"}`,
-			statusCode:   401,
+			statusCode:   403,
 			tokenGiven:   tokenString,
-			errorMessage: "Unauthorized",
+			errorMessage: "Forbidden",
 		}, {
 			id:         "unwokdn",
 			statusCode: 400,
@@ -311,8 +314,8 @@ func TestUpdatePost(t *testing.T) {
 			id:           strconv.Itoa(int(AuthPostID)),
 			updateJSON:   `{"title": "This is another title", "content": "This is updated content", "author_id": 2}`,
 			tokenGiven:   tokenString,
-			statusCode:   401,
-			errorMessage: "Unauthorized",
+			statusCode:   403,
+			errorMessage: "Forbidden",
 		},
 	}
 
@@ -342,12 +345,62 @@ func TestUpdatePost(t *testing.T) {
 			assert.Equal(t, responseMap["content"], v.content)
 			assert.Equal(t, responseMap["author_id"], float64(v.author_id))
 		}
-		if v.statusCode == 401 || v.statusCode == 422 || v.statusCode == 500 && v.errorMessage != "" {
-			assert.Equal(t, responseMap["error"], v.errorMessage)
+		if v.statusCode == 401 || v.statusCode == 403 || v.statusCode == 422 || v.statusCode == 409 && v.errorMessage != "" {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
 		}
 	}
 }
 
+func TestUpdateDraft(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	draft := models.Post{Title: "Draft", Content: "Not yet", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	draft.Prepare()
+	_, err = draft.SavePost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	samples := []struct {
+		updateJSON string
+		status     string
+	}{
+		// Clients written before drafts send no status, the draft stays one
+		{updateJSON: `{"title": "Draft", "content": "Still not yet", "author_id": %d}`, status: models.PostStatusDraft},
+		{updateJSON: `{"title": "Draft", "content": "Now", "author_id": %d, "status": "published"}`, status: models.PostStatusPublished},
+		{updateJSON: `{"title": "Draft", "content": "Again", "author_id": %d}`, status: models.PostStatusPublished},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("PUT", "/posts", bytes.NewBufferString(fmt.Sprintf(v.updateJSON, users[0].ID)))
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.FormatUint(draft.ID, 10)})
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.UpdatePost).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, http.StatusOK)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal(rr.Body.Bytes(), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, responseMap["status"], v.status)
+		stored := models.Post{}
+		err = server.DB.Where("id = ?", draft.ID).Take(&stored).Error
+		assert.Equal(t, err, nil)
+		assert.Equal(t, stored.Status, v.status)
+	}
+}
+
 func TestDeletePost(t *testing.T) {
 	var PostUserEmail, PostUserPassword string
 	var PostUserID uint32
@@ -420,9 +473,9 @@ func TestDeletePost(t *testing.T) {
 		}, {
 			id:           "1",
 			author_id:    1,
-			statusCode:   401,
+			statusCode:   403,
 			tokenGiven:   tokenString,
-			errorMessage: "Unauthorized",
+			errorMessage: "Forbidden",
 		},
 	}
 	for _, v := range postSample {
@@ -438,14 +491,150 @@ func TestDeletePost(t *testing.T) {
 
 		assert.Equal(t, rr.Code, v.statusCode)
 
-		if v.statusCode == 401 && v.errorMessage != "" {
+		if (v.statusCode == 401 || v.statusCode == 403) && v.errorMessage != "" {
 			responseMap := make(map[string]interface{})
 			err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
 			if err != nil {
 				t.Errorf("Cannot convert to json: %v", err)
 			}
-			assert.Equal(t, responseMap["error"], v.errorMessage)
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
+		}
+	}
+
+}
+
+func TestGetPostFormats(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	token, err := server.SignIn(user.Email, "password")
+	if err != nil {
+		log.Fatalf("Cannot login: %v\n", err)
+	}
+
+	// the markdown is stored as sent, without html escaping
+	inputJSON := fmt.Sprintf(`{"title": "Formats", "content": "**Tom & Jerry**", "author_id": %d}`, user.ID)
+	req, _ := http.NewRequest("POST", "/posts", bytes.NewBufferString(inputJSON))
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", token))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreatePost).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	samples := []struct {
+		format      string
+		statusCode  int
+		content     interface{}
+		contentHTML interface{}
+		contentText interface{}
+	}{
+		{
+			format:      "",
+			statusCode:  200,
+			content:     "**Tom & Jerry**",
+			contentHTML: "<p><strong>Tom &amp; Jerry</strong></p>\n",
+		}, {
+			format:     "markdown",
+			statusCode: 200,
+			content:    "**Tom & Jerry**",
+		}, {
+			format:      "html",
+			statusCode:  200,
+			contentHTML: "<p><strong>Tom &amp; Jerry</strong></p>\n",
+		}, {
+			format:      "text",
+			statusCode:  200,
+			contentText: "Tom & Jerry",
+		}, {
+			format:     "pdf",
+			statusCode: 400,
+		},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/posts?format="+v.format, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "1"})
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetPost).ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["content"], v.content)
+			assert.Equal(t, responseMap["content_html"], v.contentHTML)
+			assert.Equal(t, responseMap["content_text"], v.contentText)
 		}
 	}
+}
 
+func TestGetPostBySlug(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	oldSlug := post.Slug
+	post.Title = "A renamed title"
+	updatedPost, err := post.UpdateAPost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	samples := []struct {
+		slug         string
+		statusCode   int
+		location     string
+		errorMessage string
+	}{
+		{
+			slug:       updatedPost.Slug,
+			statusCode: 200,
+		}, {
+			slug:       oldSlug,
+			statusCode: 301,
+			location:   "/posts/by-slug/" + updatedPost.Slug,
+		}, {
+			slug:         "no-such-post",
+			statusCode:   404,
+			errorMessage: "Post not found",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("GET", "/posts/by-slug/"+v.slug, nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"slug": v.slug})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.GetPostBySlug)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, responseMap["id"], float64(post.ID))
+			assert.Equal(t, responseMap["slug"], "a-renamed-title")
+		}
+		if v.statusCode == 301 {
+			assert.Equal(t, rr.Header().Get("Location"), v.location)
+		}
+		if v.statusCode == 404 {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
+		}
+	}
 }
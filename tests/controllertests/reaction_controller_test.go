@@ -0,0 +1,241 @@
+package controllertests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestCreateReaction(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	samples := []struct {
+		id           string
+		inputJSON    string
+		tokenGiven   string
+		statusCode   int
+		errorMessage string
+	}{
+		{
+			id:         strconv.Itoa(int(post.ID)),
+			inputJSON:  `{"type": "love"}`,
+			tokenGiven: tokenString,
+			statusCode: 201,
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			inputJSON:    `{"type": "love"}`,
+			tokenGiven:   tokenString,
+			statusCode:   409,
+			errorMessage: "Already Reacted",
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			inputJSON:    `{"type": "meh"}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "Invalid Reaction Type",
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			inputJSON:    `{}`,
+			tokenGiven:   tokenString,
+			statusCode:   422,
+			errorMessage: "Required Type",
+		}, {
+			id:           "100",
+			inputJSON:    `{"type": "like"}`,
+			tokenGiven:   tokenString,
+			statusCode:   404,
+			errorMessage: "Post not found",
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			inputJSON:    `{"type": "like"}`,
+			tokenGiven:   "",
+			statusCode:   401,
+			errorMessage: "Unauthorized",
+		},
+	}
+
+	for _, v := range samples {
+		req, err := http.NewRequest("POST", "/posts/reactions", bytes.NewBufferString(v.inputJSON))
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req = mux.SetURLVars(req, map[string]string{"id": v.id})
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(server.CreateReaction)
+
+		req.Header.Set("Authorization", v.tokenGiven)
+		handler.ServeHTTP(rr, req)
+
+		responseMap := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 201 {
+			assert.Equal(t, responseMap["type"], "love")
+			assert.Equal(t, responseMap["post_id"], float64(post.ID))
+		}
+		if v.errorMessage != "" {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
+		}
+	}
+
+	// the counter and the caller's own reaction show up on the post
+	req, _ := http.NewRequest("GET", "/posts", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(post.ID))})
+	req.Header.Set("Authorization", tokenString)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetPost).ServeHTTP(rr, req)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+	reactions := responseMap["reactions"].(map[string]interface{})
+	assert.Equal(t, reactions["love"], float64(1))
+	assert.Equal(t, reactions["like"], float64(0))
+	assert.Equal(t, responseMap["my_reactions"], []interface{}{"love"})
+}
+
+func TestDeleteReaction(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	token, err := server.SignIn("sam@gmail.com", "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", token)
+
+	req, _ := http.NewRequest("POST", "/posts/reactions", bytes.NewBufferString(`{"type": "sad"}`))
+	req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(post.ID))})
+	req.Header.Set("Authorization", tokenString)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreateReaction).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	samples := []struct {
+		id           string
+		reactionType string
+		tokenGiven   string
+		statusCode   int
+	}{
+		{
+			id:           strconv.Itoa(int(post.ID)),
+			reactionType: "sad",
+			tokenGiven:   tokenString,
+			statusCode:   204,
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			reactionType: "sad",
+			tokenGiven:   tokenString,
+			statusCode:   404,
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			reactionType: "meh",
+			tokenGiven:   tokenString,
+			statusCode:   400,
+		}, {
+			id:           strconv.Itoa(int(post.ID)),
+			reactionType: "sad",
+			tokenGiven:   "Wrong token",
+			statusCode:   401,
+		},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("DELETE", "/posts/reactions", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": v.id, "type": v.reactionType})
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.DeleteReaction).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+
+	req, _ = http.NewRequest("GET", "/posts/reactions", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(post.ID))})
+	req.Header.Set("Authorization", tokenString)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetReactions).ServeHTTP(rr, req)
+
+	responseMap := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, responseMap["reactions"].(map[string]interface{})["sad"], float64(0))
+	assert.Equal(t, responseMap["my_reactions"], []interface{}{})
+}
+
+func TestGetReactionsOfDraft(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	draft := models.Post{Title: "Draft", Content: "Not yet", AuthorID: users[0].ID, Status: models.PostStatusDraft}
+	draft.Prepare()
+	_, err = draft.SavePost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	author, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	other, err := server.SignIn(users[1].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+
+	samples := []struct {
+		tokenGiven string
+		statusCode int
+	}{
+		{tokenGiven: "Bearer " + author, statusCode: 200},
+		{tokenGiven: "Bearer " + other, statusCode: 404},
+		{tokenGiven: "", statusCode: 404},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/posts/reactions", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": strconv.Itoa(int(draft.ID))})
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetReactions).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+	}
+}
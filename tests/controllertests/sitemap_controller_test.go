@@ -0,0 +1,109 @@
+package controllertests
+
+import (
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/sitemap"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+func TestGetSitemap(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, title := range []string{"Third post", "Draft post"} {
+		post := models.Post{Title: title, Content: title, AuthorID: users[0].ID}
+		if title == "Draft post" {
+			post.Status = models.PostStatusDraft
+		}
+		post.Prepare()
+		_, err = post.SavePost(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	urlsPerFile := sitemap.URLsPerFile
+	sitemap.URLsPerFile = 2
+	defer func() { sitemap.URLsPerFile = urlsPerFile }()
+
+	req, _ := http.NewRequest("GET", "/sitemap.xml", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetSitemapIndex).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 200)
+	assert.Equal(t, rr.Header().Get("Content-Type"), "application/xml; charset=utf-8")
+
+	index := sitemapDocument{}
+	err = xml.Unmarshal(rr.Body.Bytes(), &index)
+	if err != nil {
+		t.Errorf("Cannot decode the sitemap index: %v", err)
+	}
+	assert.Equal(t, index.XMLName.Local, "sitemapindex")
+	// Three published posts over two files, two users in one
+	assert.Equal(t, len(index.Sitemaps), 3)
+	if len(index.Sitemaps) == 3 {
+		assert.Equal(t, index.Sitemaps[0].Loc, "http://"+req.Host+"/sitemaps/posts-1.xml")
+		assert.Equal(t, index.Sitemaps[1].Loc, "http://"+req.Host+"/sitemaps/posts-2.xml")
+		assert.Equal(t, index.Sitemaps[2].Loc, "http://"+req.Host+"/sitemaps/users-1.xml")
+		assert.NotEqual(t, index.Sitemaps[0].LastMod, "")
+	}
+
+	samples := []struct {
+		section    string
+		page       string
+		statusCode int
+		locs       []string
+	}{
+		{section: "posts", page: "1", statusCode: 200, locs: []string{"/api/v1/posts/by-slug/title-1", "/api/v1/posts/by-slug/title-2"}},
+		{section: "posts", page: "2", statusCode: 200, locs: []string{"/api/v1/posts/by-slug/third-post"}},
+		{section: "users", page: "1", statusCode: 200, locs: []string{"/api/v1/users/1", "/api/v1/users/2"}},
+		{section: "posts", page: "3", statusCode: 404},
+		{section: "users", page: "0", statusCode: 404},
+		{section: "tags", page: "1", statusCode: 404},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/sitemaps/"+v.section+"-"+v.page+".xml", nil)
+		req = mux.SetURLVars(req, map[string]string{"section": v.section, "page": v.page})
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetSitemap).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode != 200 {
+			continue
+		}
+		doc := sitemapDocument{}
+		err = xml.Unmarshal(rr.Body.Bytes(), &doc)
+		if err != nil {
+			t.Errorf("Cannot decode the sitemap: %v", err)
+		}
+		assert.Equal(t, doc.XMLName.Local, "urlset")
+		locs := []string{}
+		for _, u := range doc.URLs {
+			locs = append(locs, u.Loc[len("http://"+req.Host):])
+			assert.NotEqual(t, u.LastMod, "")
+		}
+		assert.Equal(t, locs, v.locs)
+	}
+}
@@ -0,0 +1,226 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func seedFeedPosts() []models.User {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, content := range []string{"Learning #golang", "More **#golang** & co", "Draft #golang"} {
+		post := models.Post{Title: "Feed " + content, Content: content, AuthorID: users[0].ID}
+		if i == 2 {
+			post.Status = models.PostStatusDraft
+		}
+		post.Prepare()
+		_, err = post.SavePost(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	return users
+}
+
+func TestGetPostsFeed(t *testing.T) {
+	seedFeedPosts()
+
+	samples := []struct {
+		format      string
+		query       string
+		statusCode  int
+		contentType string
+		length      int
+	}{
+		{format: "rss", statusCode: 200, contentType: "application/rss+xml; charset=utf-8", length: 4},
+		{format: "atom", statusCode: 200, contentType: "application/atom+xml; charset=utf-8", length: 4},
+		{format: "json", statusCode: 200, contentType: "application/feed+json; charset=utf-8", length: 4},
+		{format: "json", query: "?limit=2", statusCode: 200, contentType: "application/feed+json; charset=utf-8", length: 2},
+		{format: "rss", query: "?limit=0", statusCode: 400},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/feeds/posts."+v.format+v.query, nil)
+		req = mux.SetURLVars(req, map[string]string{"format": v.format})
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetPostsFeed).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode != 200 {
+			continue
+		}
+		assert.Equal(t, rr.Header().Get("Content-Type"), v.contentType)
+		assert.NotEqual(t, rr.Header().Get("ETag"), "")
+		assert.NotEqual(t, rr.Header().Get("Last-Modified"), "")
+
+		titles := feedTitles(t, v.format, rr.Body.Bytes())
+		assert.Equal(t, len(titles), v.length)
+		// The newest post comes first and its title is not escaped twice
+		assert.Equal(t, titles[0], "Feed More **#golang** & co")
+	}
+}
+
+func TestGetPostsFeedConditional(t *testing.T) {
+	seedFeedPosts()
+
+	req, _ := http.NewRequest("GET", "/feeds/posts.atom", nil)
+	req = mux.SetURLVars(req, map[string]string{"format": "atom"})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetPostsFeed).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 200)
+	etag := rr.Header().Get("ETag")
+	lastModified := rr.Header().Get("Last-Modified")
+
+	samples := []struct {
+		header     string
+		value      string
+		statusCode int
+	}{
+		{header: "If-None-Match", value: etag, statusCode: 304},
+		{header: "If-None-Match", value: `"stale"`, statusCode: 200},
+		{header: "If-Modified-Since", value: lastModified, statusCode: 304},
+		{header: "If-Modified-Since", value: "Mon, 02 Jan 2006 15:04:05 GMT", statusCode: 200},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/feeds/posts.atom", nil)
+		req = mux.SetURLVars(req, map[string]string{"format": "atom"})
+		req.Header.Set(v.header, v.value)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetPostsFeed).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 304 {
+			assert.Equal(t, rr.Body.Len(), 0)
+		}
+	}
+
+	// The same feed in another format is another representation
+	req, _ = http.NewRequest("GET", "/feeds/posts.rss", nil)
+	req = mux.SetURLVars(req, map[string]string{"format": "rss"})
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(server.GetPostsFeed).ServeHTTP(rr, req)
+	assert.NotEqual(t, rr.Header().Get("ETag"), etag)
+}
+
+func TestGetUserAndTagPostsFeed(t *testing.T) {
+	users := seedFeedPosts()
+
+	userSamples := []struct {
+		id         string
+		statusCode int
+		length     int
+	}{
+		{id: strconv.Itoa(int(users[0].ID)), statusCode: 200, length: 3},
+		{id: strconv.Itoa(int(users[1].ID)), statusCode: 200, length: 1},
+		{id: "100", statusCode: 404},
+		{id: "unknown", statusCode: 400},
+	}
+	for _, v := range userSamples {
+		req, _ := http.NewRequest("GET", "/feeds/users/posts.json", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": v.id, "format": "json"})
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetUserPostsFeed).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, len(feedTitles(t, "json", rr.Body.Bytes())), v.length)
+		}
+	}
+
+	tagSamples := []struct {
+		name       string
+		statusCode int
+		length     int
+	}{
+		{name: "golang", statusCode: 200, length: 2},
+		{name: "GoLang", statusCode: 200, length: 2},
+		{name: "unknown", statusCode: 404},
+	}
+	for _, v := range tagSamples {
+		req, _ := http.NewRequest("GET", "/feeds/tags/posts.rss", nil)
+		req = mux.SetURLVars(req, map[string]string{"name": v.name, "format": "rss"})
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetTagPostsFeed).ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			assert.Equal(t, len(feedTitles(t, "rss", rr.Body.Bytes())), v.length)
+		}
+	}
+
+	// The links of the feed of a tag escape its name like the links of the content
+	post := models.Post{Title: "Café", Content: "At the #café", AuthorID: users[0].ID}
+	post.Prepare()
+	_, err := post.SavePost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", "/feeds/tags/caf%C3%A9/posts.json", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "café", "format": "json"})
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.GetTagPostsFeed).ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, 200)
+	doc := struct {
+		HomePageURL string `json:"home_page_url"`
+		FeedURL     string `json:"feed_url"`
+	}{}
+	err = json.Unmarshal(rr.Body.Bytes(), &doc)
+	if err != nil {
+		t.Errorf("Cannot decode the json feed: %v", err)
+	}
+	assert.Equal(t, doc.HomePageURL, "http://"+req.Host+"/api/v1/tags/caf%C3%A9/posts")
+	assert.Equal(t, doc.FeedURL, "http://"+req.Host+"/feeds/tags/caf%C3%A9/posts.json")
+}
+
+// feedTitles decodes the item titles of a feed in any of the formats
+func feedTitles(t *testing.T, format string, body []byte) []string {
+	titles := []string{}
+	var err error
+	switch format {
+	case "rss":
+		doc := struct {
+			Items []struct {
+				Title string `xml:"title"`
+			} `xml:"channel>item"`
+		}{}
+		err = xml.Unmarshal(body, &doc)
+		for _, item := range doc.Items {
+			titles = append(titles, item.Title)
+		}
+	case "atom":
+		doc := struct {
+			Entries []struct {
+				Title string `xml:"title"`
+			} `xml:"entry"`
+		}{}
+		err = xml.Unmarshal(body, &doc)
+		for _, entry := range doc.Entries {
+			titles = append(titles, entry.Title)
+		}
+	case "json":
+		doc := struct {
+			Items []struct {
+				Title string `json:"title"`
+			} `json:"items"`
+		}{}
+		err = json.Unmarshal(body, &doc)
+		for _, item := range doc.Items {
+			titles = append(titles, item.Title)
+		}
+	}
+	if err != nil {
+		t.Errorf("Cannot decode the %s feed: %v", format, err)
+	}
+	return titles
+}
@@ -0,0 +1,67 @@
+package controllertests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestGetTagPosts(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i, content := range []string{"Learning #golang", "More #GoLang", "Draft #golang"} {
+		post := models.Post{Title: content, Content: content, AuthorID: users[0].ID}
+		if i == 2 {
+			post.Status = models.PostStatusDraft
+		}
+		post.Prepare()
+		_, err = post.SavePost(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	samples := []struct {
+		name       string
+		query      string
+		statusCode int
+		length     int
+	}{
+		{name: "golang", statusCode: 200, length: 2},
+		{name: "GoLang", statusCode: 200, length: 2},
+		{name: "golang", query: "?limit=1", statusCode: 200, length: 1},
+		{name: "unknown", statusCode: 200, length: 0},
+		{name: "golang", query: "?offset=-1", statusCode: 400},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/tags/posts"+v.query, nil)
+		req = mux.SetURLVars(req, map[string]string{"name": v.name})
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(server.GetTagPosts).ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			var posts []models.Post
+			err = json.Unmarshal([]byte(rr.Body.String()), &posts)
+			if err != nil {
+				t.Errorf("Could not unmarshal: %v\n", err)
+			}
+			assert.Equal(t, len(posts), v.length)
+			for _, post := range posts {
+				assert.Equal(t, post.Tags, []string{"golang"})
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package controllertests
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,7 +12,9 @@ import (
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/apperrors"
 	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/responses"
 	"gopkg.in/go-playground/assert.v1"
 )
 
@@ -36,12 +39,12 @@ func TestCreateUser(t *testing.T) {
 		},
 		{
 			inputJSON:    `{"nickname":"Frank", "email": "pet@gmail.com", "password": "password"}`,
-			statusCode:   500,
+			statusCode:   409,
 			errorMessage: "Email Already Taken",
 		},
 		{
 			inputJSON:    `{"nickname":"Pet", "email": "grand@gmail.com", "password": "password"}`,
-			statusCode:   500,
+			statusCode:   409,
 			errorMessage: "Nickname Already Taken",
 		},
 		{
@@ -86,8 +89,8 @@ func TestCreateUser(t *testing.T) {
 			assert.Equal(t, responseMap["nickname"], v.nickname)
 			assert.Equal(t, responseMap["email"], v.email)
 		}
-		if v.statusCode == 422 || v.statusCode == 500 && v.errorMessage != "" {
-			assert.Equal(t, responseMap["error"], v.errorMessage)
+		if v.statusCode == 422 || v.statusCode == 409 && v.errorMessage != "" {
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
 		}
 	}
 }
@@ -243,14 +246,14 @@ func TestUpdateUser(t *testing.T) {
 			//remember kenny@gmail.com" belongs to user 2
 			id:           strconv.Itoa(int(AuthID)),
 			updateJSON:   `{"nickname":"Frank", "email":"kenny@gmail.com","password": "password"}`,
-			statusCode:   500,
+			statusCode:   409,
 			tokenGiven:   tokenString,
 			errorMessage: "Email Already Taken",
 		}, {
 			//remember "Kenny Morris" belongs to user 2
 			id:           strconv.Itoa(int(AuthID)),
 			updateJSON:   `{"nickname": "Kenny Morris", "email": "newemai@gmail.com", "password": "password123"}`,
-			statusCode:   500,
+			statusCode:   409,
 			tokenGiven:   tokenString,
 			errorMessage: "Nickname Already Taken",
 		}, {
@@ -273,9 +276,9 @@ func TestUpdateUser(t *testing.T) {
 			//when user2 is using user1 token
 			id:           strconv.Itoa(int(2)),
 			updateJSON:   `{"nickname": "Mike", "email":"mike@gmail.com", "password": "mypassword"}`,
-			statusCode:   401,
+			statusCode:   403,
 			tokenGiven:   tokenString,
-			errorMessage: "Unauthorized",
+			errorMessage: "Forbidden",
 		},
 	}
 
@@ -302,8 +305,8 @@ func TestUpdateUser(t *testing.T) {
 		if v.statusCode == 200 {
 			assert.Equal(t, responseMap["nickname"], v.updateNickname)
 			assert.Equal(t, responseMap["email"], v.updateEmail)
-		} else if v.statusCode == 401 || v.statusCode == 422 || v.statusCode == 500 && v.errorMessage != "" {
-			assert.Equal(t, v.errorMessage, responseMap["error"])
+		} else if v.statusCode == 401 || v.statusCode == 403 || v.statusCode == 422 || v.statusCode == 409 && v.errorMessage != "" {
+			assert.Equal(t, v.errorMessage, responseMap["detail"])
 		}
 	}
 }
@@ -370,8 +373,8 @@ func TestDeleteUser(t *testing.T) {
 			// user2 trying to use User1 token
 			id:           "2",
 			tokenGiven:   tokenString,
-			statusCode:   401,
-			errorMessage: "Unauthorized",
+			statusCode:   403,
+			errorMessage: "Forbidden",
 		},
 	}
 	for _, v := range userSample {
@@ -389,13 +392,52 @@ func TestDeleteUser(t *testing.T) {
 		handler.ServeHTTP(rr, req)
 		assert.Equal(t, rr.Code, v.statusCode)
 
-		if v.statusCode == 401 && v.errorMessage != "" {
+		if (v.statusCode == 401 || v.statusCode == 403) && v.errorMessage != "" {
 			responseMap := make(map[string]interface{})
 			err = json.Unmarshal([]byte(rr.Body.String()), &responseMap)
 			if err != nil {
 				t.Errorf("Cannot convert to json: %v\n", err)
 			}
-			assert.Equal(t, responseMap["error"], v.errorMessage)
+			assert.Equal(t, responseMap["detail"], v.errorMessage)
 		}
 	}
 }
+
+func TestCreateUserProblemDetails(t *testing.T) {
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", "/users", bytes.NewBufferString(`{"nickname": "", "email": "kangmail.com", "password": ""}`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(server.CreateUser).ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 422)
+	assert.Equal(t, rr.Header().Get("Content-Type"), responses.ProblemContentType)
+	problem := responses.ProblemDetails{}
+	err = json.Unmarshal(rr.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatalf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, problem.Type, "/problems/validation")
+	assert.Equal(t, problem.Title, "Unprocessable Entity")
+	assert.Equal(t, problem.Status, 422)
+	assert.Equal(t, problem.Detail, "Required Nickname; Required Password; Invalid Email")
+	assert.Equal(t, problem.Errors, []apperrors.FieldError{
+		{Field: "nickname", Message: "Required Nickname"},
+		{Field: "password", Message: "Required Password"},
+		{Field: "email", Message: "Invalid Email"},
+	})
+
+	// The message of an internal error stays in the logs
+	rr = httptest.NewRecorder()
+	responses.Problem(rr, errors.New("pq: connection refused"))
+	assert.Equal(t, rr.Code, 500)
+	problem = responses.ProblemDetails{}
+	err = json.Unmarshal(rr.Body.Bytes(), &problem)
+	if err != nil {
+		t.Fatalf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, problem.Type, "/problems/internal")
+	assert.Equal(t, problem.Detail, "The server could not complete the request")
+}
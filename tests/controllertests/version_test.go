@@ -0,0 +1,71 @@
+package controllertests
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/controllers"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// TestDeprecatedRoutes checks the routes served at the root before versions existed
+// answer like their /api/v1 successors, with the headers announcing their sunset
+func TestDeprecatedRoutes(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.InitializeRouter()
+
+	samples := []struct {
+		method     string
+		path       string
+		statusCode int
+	}{
+		{method: "GET", path: "/users", statusCode: 200},
+		{method: "GET", path: fmt.Sprintf("/users/%d", users[0].ID), statusCode: 200},
+		{method: "GET", path: fmt.Sprintf("/posts/%d", posts[0].ID), statusCode: 200},
+		{method: "GET", path: "/posts/by-slug/" + posts[0].Slug, statusCode: 200},
+		{method: "GET", path: "/posts/999", statusCode: 404},
+		{method: "GET", path: "/feed", statusCode: 401},
+		{method: "PATCH", path: "/posts", statusCode: 405},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest(v.method, v.path, nil)
+		rr := httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+		successorReq, _ := http.NewRequest(v.method, "/api/v1"+v.path, nil)
+		successor := httptest.NewRecorder()
+		server.Router.ServeHTTP(successor, successorReq)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		assert.Equal(t, successor.Code, v.statusCode)
+		assert.Equal(t, successor.Header().Get("Deprecation"), "")
+		if v.statusCode == http.StatusOK {
+			assert.Equal(t, rr.Body.String(), successor.Body.String())
+		}
+		if v.statusCode == http.StatusMethodNotAllowed {
+			// No route matched, the router answered
+			continue
+		}
+		assert.Equal(t, rr.Header().Get("Deprecation"), fmt.Sprintf("@%d", controllers.DeprecatedSince.Unix()))
+		assert.Equal(t, rr.Header().Get("Sunset"), controllers.SunsetAt.Format(http.TimeFormat))
+		assert.Equal(t, rr.Header().Get("Link"), fmt.Sprintf("</api/v1%s>; rel=\"successor-version\"", v.path))
+	}
+
+	// The routes that are not versioned are not deprecated
+	for _, path := range []string{"/", "/healthz", "/feeds/posts.rss", "/sitemap.xml", "/openapi.json"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		server.Router.ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, http.StatusOK)
+		assert.Equal(t, rr.Header().Get("Deprecation"), "")
+	}
+}
@@ -0,0 +1,183 @@
+package databasetests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/planutim/postgres-copy/api/apperrors"
+	"github.com/planutim/postgres-copy/api/config"
+	"github.com/planutim/postgres-copy/api/database"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDSN(t *testing.T) {
+	cfg := config.DBConfig{Host: "db", Port: "3306", User: "steven", Password: "secret", Name: "api"}
+	samples := []struct {
+		driver string
+		dsn    string
+	}{
+		{
+			driver: "postgres",
+			dsn:    "host=db port=3306 user=steven dbname=api sslmode=disable password=secret",
+		}, {
+			driver: "mysql",
+			dsn:    "steven:secret@tcp(db:3306)/api?charset=utf8mb4&parseTime=True&loc=Local",
+		}, {
+			driver: "sqlite3",
+			dsn:    "api?_busy_timeout=5000",
+		},
+	}
+	for _, v := range samples {
+		d, err := database.Lookup(v.driver)
+		if err != nil {
+			t.Errorf("this is the error: %v", err)
+			continue
+		}
+		assert.Equal(t, d.Name(), v.driver)
+		assert.Equal(t, d.DSN(cfg), v.dsn)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	_, err := database.Open(config.DBConfig{Driver: "oracle", Name: "api"})
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, strings.Contains(err.Error(), "mysql, postgres, sqlite3"), true)
+
+	// The pool of an in-memory database is a single connection, whatever the configuration
+	db, err := database.Open(config.DBConfig{Driver: "sqlite3", Name: ":memory:", MaxOpenConns: 25, MaxIdleConns: 25})
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	defer db.Close()
+	assert.Equal(t, db.DB().Stats().MaxOpenConnections, 1)
+
+	err = db.Exec("CREATE TABLE things (id integer)").Error
+	assert.Equal(t, err, nil)
+	d, err := database.DialectOf(db)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, d.Name(), "sqlite3")
+}
+
+func TestTranslateError(t *testing.T) {
+	db, err := database.Open(config.DBConfig{Driver: "sqlite3", Name: ":memory:"})
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	defer db.Close()
+	err = db.Exec(`CREATE TABLE lists (id integer PRIMARY KEY, user_id integer NOT NULL, name varchar(10) NOT NULL CHECK (name <> 'forbidden'), UNIQUE (user_id, name))`).Error
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	err = db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Later')").Error
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+
+	samples := []struct {
+		name    string
+		err     error
+		kind    apperrors.Kind
+		message string
+		fields  []string
+	}{
+		{
+			name:    "sqlite unique",
+			err:     db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'Later')").Error,
+			kind:    apperrors.Conflict,
+			message: "Name Already Taken",
+			fields:  []string{"name"},
+		}, {
+			name:    "sqlite not null",
+			err:     db.Exec("INSERT INTO lists (user_id) VALUES (1)").Error,
+			kind:    apperrors.Validation,
+			message: "Required Name",
+			fields:  []string{"name"},
+		}, {
+			name:    "sqlite check",
+			err:     db.Exec("INSERT INTO lists (user_id, name) VALUES (1, 'forbidden')").Error,
+			kind:    apperrors.Validation,
+			message: "Invalid Value",
+		}, {
+			name:    "postgres unique",
+			err:     &pq.Error{Code: "23505", Constraint: "users_email_key", Detail: "Key (email)=(steven@gmail.com) already exists."},
+			kind:    apperrors.Conflict,
+			message: "Email Already Taken",
+			fields:  []string{"email"},
+		}, {
+			name:    "postgres unique on a user",
+			err:     &pq.Error{Code: "23505", Constraint: "idx_posts_author_title", Detail: "Key (title, author_id)=(Title, 1) already exists."},
+			kind:    apperrors.Conflict,
+			message: "Title Already Taken",
+			fields:  []string{"title"},
+		}, {
+			name:    "postgres foreign key",
+			err:     &pq.Error{Code: "23503", Detail: `Key (author_id)=(9) is not present in table "users".`},
+			kind:    apperrors.Validation,
+			message: "Unknown Author",
+			fields:  []string{"author_id"},
+		}, {
+			name:    "postgres still referenced",
+			err:     &pq.Error{Code: "23503", Detail: `Key (id)=(1) is still referenced from table "posts".`},
+			kind:    apperrors.Conflict,
+			message: "Still Referenced",
+		}, {
+			name:    "postgres not null",
+			err:     &pq.Error{Code: "23502", Column: "nickname"},
+			kind:    apperrors.Validation,
+			message: "Required Nickname",
+			fields:  []string{"nickname"},
+		}, {
+			name:    "mysql unique index",
+			err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Title-1' for key 'posts.idx_posts_author_title'"},
+			kind:    apperrors.Conflict,
+			message: "Title Already Taken",
+			fields:  []string{"title"},
+		}, {
+			name:    "mysql unique column",
+			err:     &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'Kenny' for key 'nickname'"},
+			kind:    apperrors.Conflict,
+			message: "Nickname Already Taken",
+			fields:  []string{"nickname"},
+		}, {
+			name:    "mysql too long",
+			err:     &mysql.MySQLError{Number: 1406, Message: "Data too long for column 'excerpt' at row 1"},
+			kind:    apperrors.Validation,
+			message: "Excerpt Too Long",
+			fields:  []string{"excerpt"},
+		}, {
+			name:    "mysql foreign key",
+			err:     &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`api`.`posts`, CONSTRAINT `fk_posts_author` FOREIGN KEY (`author_id`) REFERENCES `users` (`id`))"},
+			kind:    apperrors.Validation,
+			message: "Unknown Author",
+			fields:  []string{"author_id"},
+		}, {
+			name:    "not a violation",
+			err:     &pq.Error{Code: "42P01", Message: `relation "nothings" does not exist`},
+			kind:    apperrors.Internal,
+			message: `pq: relation "nothings" does not exist`,
+		},
+	}
+	for _, v := range samples {
+		err := database.TranslateError(v.err)
+		if err == nil {
+			t.Errorf("%s: no error", v.name)
+			continue
+		}
+		assert.Equal(t, apperrors.KindOf(err), v.kind)
+		assert.Equal(t, err.Error(), v.message)
+		fields := []string{}
+		for _, f := range apperrors.Fields(err) {
+			fields = append(fields, f.Field)
+		}
+		if v.fields == nil {
+			v.fields = []string{}
+		}
+		assert.Equal(t, fields, v.fields)
+		// The driver error is still there for the logs
+		assert.Equal(t, errors.Is(err, v.err), true)
+	}
+	assert.Equal(t, database.TranslateError(nil), nil)
+}
@@ -0,0 +1,184 @@
+package loggingtests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/auth"
+	"github.com/planutim/postgres-copy/api/config"
+	"github.com/planutim/postgres-copy/api/controllers"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/logging"
+	"github.com/planutim/postgres-copy/api/middlewares"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/requestid"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+// capture makes a JSON logger writing to the returned buffer the default one for
+// the duration of the test
+func capture(t *testing.T, level slog.Level) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	previous := slog.Default()
+	slog.SetDefault(logging.New(buf, level))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return buf
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	lines := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := map[string]interface{}{}
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatalf("Cannot convert to json: %v", err)
+		}
+		lines = append(lines, record)
+	}
+	return lines
+}
+
+func TestParseLevel(t *testing.T) {
+	samples := []struct {
+		level    string
+		expected slog.Level
+		valid    bool
+	}{
+		{"debug", slog.LevelDebug, true},
+		{"INFO", slog.LevelInfo, true},
+		{"warn", slog.LevelWarn, true},
+		{"error", slog.LevelError, true},
+		{"loud", slog.LevelInfo, false},
+	}
+	for _, v := range samples {
+		level, err := logging.ParseLevel(v.level)
+		assert.Equal(t, level, v.expected)
+		assert.Equal(t, err == nil, v.valid)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	auth.Configure("logging secret", time.Hour)
+	token, err := auth.CreateToken(7)
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	buf := capture(t, slog.LevelInfo)
+
+	router := mux.NewRouter()
+	router.Use(requestid.Middleware, logging.Middleware)
+	router.HandleFunc("/posts/{id}", middlewares.SetMiddlewareAuthentication(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	samples := []struct {
+		requestID string
+		query     string
+		status    float64
+		userID    interface{}
+	}{
+		{requestID: "abc-123", query: "?token=" + token, status: 204, userID: float64(7)},
+		{requestID: "not a valid id\n", status: 401},
+	}
+	for _, v := range samples {
+		buf.Reset()
+		req, _ := http.NewRequest("GET", "/posts/12"+v.query, nil)
+		req.Header.Set("X-Request-ID", v.requestID)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, strings.Contains(buf.String(), token), false)
+		lines := records(t, buf)
+		last := lines[len(lines)-1]
+		assert.Equal(t, last["msg"], "request")
+		assert.Equal(t, last["route"], "/posts/{id}")
+		assert.Equal(t, last["path"], "/posts/12")
+		assert.Equal(t, last["status"], v.status)
+		assert.Equal(t, last["user_id"], v.userID)
+		if v.status == 204 {
+			assert.Equal(t, len(lines), 2)
+			assert.Equal(t, lines[0]["msg"], "handled")
+			assert.Equal(t, lines[0]["request_id"], v.requestID)
+			assert.Equal(t, lines[0]["user_id"], v.userID)
+		} else {
+			// An unsafe request ID is replaced by a new one
+			assert.Equal(t, len(last["request_id"].(string)), 32)
+		}
+	}
+}
+
+func TestGormLogger(t *testing.T) {
+	db, err := database.Open(config.DBConfig{Driver: "sqlite3", Name: ":memory:"})
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	defer db.Close()
+	err = db.Exec("CREATE TABLE users (id integer primary key, nickname varchar(255), email varchar(100), password varchar(100), created_at datetime, updated_at datetime)").Error
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+
+	samples := []struct {
+		level     slog.Level
+		threshold time.Duration
+		messages  []string
+	}{
+		{level: slog.LevelInfo, threshold: time.Hour, messages: []string{}},
+		{level: slog.LevelInfo, threshold: time.Nanosecond, messages: []string{"slow query"}},
+		{level: slog.LevelDebug, threshold: time.Hour, messages: []string{"query"}},
+	}
+	for i, v := range samples {
+		buf := &bytes.Buffer{}
+		db.SetLogger(logging.GormLogger{Logger: logging.New(buf, v.level), SlowThreshold: v.threshold})
+		db.LogMode(true)
+
+		email := fmt.Sprintf("secret%d@gmail.com", i)
+		err = db.Create(&models.User{Nickname: fmt.Sprintf("pet%d", i), Email: email, Password: "s3cr3t-hash"}).Error
+		assert.Equal(t, err, nil)
+		assert.Equal(t, strings.Contains(buf.String(), email), false)
+		assert.Equal(t, strings.Contains(buf.String(), "s3cr3t-hash"), false)
+
+		messages := []string{}
+		for _, record := range records(t, buf) {
+			messages = append(messages, record["msg"].(string))
+			assert.Equal(t, strings.Contains(record["sql"].(string), "INSERT INTO"), true)
+		}
+		assert.Equal(t, messages, v.messages)
+	}
+
+	buf := &bytes.Buffer{}
+	db.SetLogger(logging.GormLogger{Logger: logging.New(buf, slog.LevelError), SlowThreshold: time.Hour})
+	db.Table("nothings").Find(&[]models.User{})
+	lines := records(t, buf)
+	assert.Equal(t, len(lines), 1)
+	assert.Equal(t, lines[0]["msg"], "query failed")
+	assert.Equal(t, lines[0]["error"], "no such table: nothings")
+
+	// Connect logs the errors even when no statement is logged
+	buf = capture(t, slog.LevelInfo)
+	server := controllers.Server{}
+	err = server.Connect(config.DBConfig{Driver: "sqlite3", Name: ":memory:"}, config.LogConfig{Level: "info"})
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	defer server.DB.Close()
+	buf.Reset()
+	server.DB.Exec("CREATE TABLE users (id integer primary key)")
+	server.DB.Table("nothings").Find(&[]models.User{})
+	messages := []string{}
+	for _, record := range records(t, buf) {
+		messages = append(messages, record["msg"].(string))
+	}
+	assert.Equal(t, messages, []string{"query failed"})
+}
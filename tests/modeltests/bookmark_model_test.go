@@ -0,0 +1,114 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var bookmarkInstance = models.Bookmark{}
+
+func TestSaveBookmark(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	post, err := seedOneUserAndPost()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+
+	list := models.ReadingList{
+		UserID: post.AuthorID,
+		Name:   "Later",
+	}
+	savedList, err := list.SaveReadingList(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the reading list: %v\n", err)
+		return
+	}
+
+	newBookmark := models.Bookmark{
+		UserID:        post.AuthorID,
+		PostID:        post.ID,
+		ReadingListID: savedList.ID,
+	}
+	savedBookmark, err := newBookmark.SaveBookmark(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the bookmark: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedBookmark.PostID, post.ID)
+	assert.Equal(t, savedBookmark.ReadingListID, savedList.ID)
+
+	bookmarks, err := bookmarkInstance.FindUserBookmarks(server.DB, post.AuthorID, &savedList.ID, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the bookmarks: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*bookmarks), 1)
+	assert.Equal(t, (*bookmarks)[0].Available, true)
+	assert.Equal(t, (*bookmarks)[0].Post.Title, post.Title)
+
+	// deleting the list keeps the bookmark
+	_, err = list.DeleteAReadingList(server.DB, savedList.ID, post.AuthorID)
+	if err != nil {
+		t.Errorf("this is the error deleting the reading list: %v\n", err)
+		return
+	}
+	bookmarks, err = bookmarkInstance.FindUserBookmarks(server.DB, post.AuthorID, nil, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the bookmarks: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*bookmarks), 1)
+	assert.Equal(t, (*bookmarks)[0].ReadingListID, uint64(0))
+}
+
+func TestFindUserBookmarksOfUnavailablePosts(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+
+	for _, post := range posts {
+		bookmark := models.Bookmark{UserID: users[0].ID, PostID: post.ID}
+		_, err = bookmark.SaveBookmark(server.DB)
+		if err != nil {
+			log.Fatalf("Error seeding bookmarks %v\n", err)
+		}
+	}
+	// the second post goes back to draft, the first one is deleted
+	err = server.DB.Model(&models.Post{}).Where("id = ?", posts[1].ID).UpdateColumn("status", models.PostStatusDraft).Error
+	if err != nil {
+		log.Fatalf("Error updating post %v\n", err)
+	}
+	_, err = postInstance.DeleteAPost(server.DB, posts[0].ID, posts[0].AuthorID)
+	if err != nil {
+		log.Fatalf("Error deleting post %v\n", err)
+	}
+
+	bookmarks, err := bookmarkInstance.FindUserBookmarks(server.DB, users[0].ID, nil, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the bookmarks: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*bookmarks), 2)
+	for _, bookmark := range *bookmarks {
+		assert.Equal(t, bookmark.Available, false)
+		assert.Equal(t, bookmark.Post == nil, true)
+	}
+
+	isDeleted, err := bookmarkInstance.DeleteBookmark(server.DB, users[0].ID, posts[0].ID)
+	if err != nil {
+		t.Errorf("this is the error deleting the bookmark: %v\n", err)
+		return
+	}
+	assert.Equal(t, isDeleted, int64(1))
+}
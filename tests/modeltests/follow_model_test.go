@@ -0,0 +1,144 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var followInstance = models.Follow{}
+
+func TestSaveFollow(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+
+	newFollow := models.Follow{
+		FollowerID:  users[0].ID,
+		FollowingID: users[1].ID,
+	}
+	savedFollow, err := newFollow.SaveFollow(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the follow: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedFollow.FollowerID, users[0].ID)
+	assert.Equal(t, savedFollow.FollowingID, users[1].ID)
+
+	following, err := followInstance.IsFollowing(server.DB, users[0].ID, users[1].ID)
+	if err != nil {
+		t.Errorf("this is the error checking the follow: %v\n", err)
+		return
+	}
+	assert.Equal(t, following, true)
+
+	// following a user that does not exist
+	unknownFollow := models.Follow{
+		FollowerID:  users[0].ID,
+		FollowingID: 100,
+	}
+	_, err = unknownFollow.SaveFollow(server.DB)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestFindFollowersAndFollowing(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, _, err := seedUsersAndFollows()
+	if err != nil {
+		log.Fatalf("Error seeding follows %v\n", err)
+	}
+
+	followers, err := followInstance.FindFollowers(server.DB, users[1].ID, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the followers: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*followers), 1)
+	assert.Equal(t, (*followers)[0].ID, users[0].ID)
+
+	following, err := followInstance.FindFollowing(server.DB, users[0].ID, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the following: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*following), 1)
+	assert.Equal(t, (*following)[0].ID, users[1].ID)
+
+	following, err = followInstance.FindFollowing(server.DB, users[0].ID, 10, 1)
+	if err != nil {
+		t.Errorf("this is the error getting the following: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*following), 0)
+}
+
+func TestDeleteFollow(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, _, err := seedUsersAndFollows()
+	if err != nil {
+		log.Fatalf("Error seeding follows %v\n", err)
+	}
+
+	isDeleted, err := followInstance.DeleteFollow(server.DB, users[0].ID, users[1].ID)
+	if err != nil {
+		t.Errorf("this is the error deleting the follow: %v\n", err)
+		return
+	}
+	assert.Equal(t, isDeleted, int64(1))
+
+	_, err = followInstance.DeleteFollow(server.DB, users[0].ID, users[1].ID)
+	assert.Equal(t, err.Error(), "Not Following")
+}
+
+func TestFindFeed(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, posts, err := seedUsersAndFollows()
+	if err != nil {
+		log.Fatalf("Error seeding follows %v\n", err)
+	}
+
+	// a draft by the followed user stays out of the feed
+	draft := models.Post{
+		Title:    "Draft title",
+		Content:  "Draft content",
+		AuthorID: users[1].ID,
+		Status:   models.PostStatusDraft,
+	}
+	err = server.DB.Model(&models.Post{}).Create(&draft).Error
+	if err != nil {
+		log.Fatalf("Cannot seed draft: %v\n", err)
+	}
+
+	feed, err := postInstance.FindFeed(server.DB, users[0].ID, time.Time{}, 0, 10)
+	if err != nil {
+		t.Errorf("this is the error getting the feed: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*feed), 1)
+	assert.Equal(t, (*feed)[0].ID, posts[1].ID)
+	assert.Equal(t, (*feed)[0].Author.ID, users[1].ID)
+
+	feed, err = postInstance.FindFeed(server.DB, users[0].ID, posts[1].CreatedAt, posts[1].ID, 10)
+	if err != nil {
+		t.Errorf("this is the error getting the feed: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*feed), 0)
+}
@@ -0,0 +1,215 @@
+package modeltests
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestSavePostWithMentionsAndHashtags(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+	mentioned := models.User{
+		Nickname: "magu",
+		Email:    "magu.frank@gmail.com",
+		Password: "password",
+	}
+	err = server.DB.Model(&models.User{}).Create(&mentioned).Error
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+
+	newPost := models.Post{
+		Title:    "Mentions",
+		Content:  "Thanks @Magu and @nobody for #GoLang tips",
+		AuthorID: users[0].ID,
+	}
+	newPost.Prepare()
+	savedPost, err := newPost.SavePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedPost.Tags, []string{"golang"})
+	assert.Equal(t, savedPost.Mentions, []string{"magu"})
+	assert.Equal(t, savedPost.ContentHTML, "<p>Thanks <a href=\"/api/v1/users/3\" class=\"mention\" rel=\"nofollow\">@Magu</a> and @nobody for <a href=\"/api/v1/tags/golang/posts\" class=\"hashtag\" rel=\"nofollow\">#GoLang</a> tips</p>\n")
+
+	notification := models.Notification{}
+	notifications, err := notification.FindUserNotifications(server.DB, mentioned.ID, true, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 1)
+	assert.Equal(t, (*notifications)[0].ActorID, users[0].ID)
+	assert.Equal(t, (*notifications)[0].Kind, models.NotificationMention)
+
+	// editing the post keeps the mention without notifying twice, and drops the tag
+	postUpdate := models.Post{
+		Title:    "Mentions",
+		Content:  "Thanks again @magu",
+		AuthorID: users[0].ID,
+	}
+	postUpdate.Prepare()
+	postUpdate.ID = savedPost.ID
+	updatedPost, err := postUpdate.UpdateAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, updatedPost.Tags, []string{})
+	assert.Equal(t, updatedPost.Mentions, []string{"magu"})
+
+	notifications, err = notification.FindUserNotifications(server.DB, mentioned.ID, false, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 1)
+
+	read, err := notification.MarkAsRead(server.DB, (*notifications)[0].ID, mentioned.ID)
+	if err != nil {
+		t.Errorf("this is the error reading the notification: %v\n", err)
+		return
+	}
+	assert.NotEqual(t, read.ReadAt, nil)
+}
+
+func TestMentionsInDraftsNotifyOnPublish(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+	mentioned := models.User{
+		Nickname: "magu",
+		Email:    "magu.frank@gmail.com",
+		Password: "password",
+	}
+	err = server.DB.Model(&models.User{}).Create(&mentioned).Error
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+
+	notification := models.Notification{}
+	samples := []struct {
+		content       string
+		status        string
+		notifications int
+	}{
+		// a draft records the mention without notifying
+		{content: "Thanks @magu", status: models.PostStatusDraft, notifications: 0},
+		{content: "Thanks again @magu", status: models.PostStatusDraft, notifications: 0},
+		// publishing notifies the users mentioned while it was a draft
+		{content: "Thanks again @magu", status: models.PostStatusPublished, notifications: 1},
+		{content: "Thanks once more @magu", status: models.PostStatusPublished, notifications: 1},
+	}
+	var pid uint64
+	for i, v := range samples {
+		post := models.Post{Title: "Mentions", Content: v.content, Status: v.status, AuthorID: users[0].ID}
+		post.Prepare()
+		var saved *models.Post
+		if i == 0 {
+			saved, err = post.SavePost(server.DB)
+		} else {
+			post.ID = pid
+			saved, err = post.UpdateAPost(server.DB)
+		}
+		if err != nil {
+			t.Errorf("this is the error saving the post: %v\n", err)
+			return
+		}
+		pid = saved.ID
+		assert.Equal(t, saved.Mentions, []string{"magu"})
+
+		notifications, err := notification.FindUserNotifications(server.DB, mentioned.ID, false, 10, 0)
+		if err != nil {
+			t.Errorf("this is the error getting the notifications: %v\n", err)
+			return
+		}
+		assert.Equal(t, len(*notifications), v.notifications)
+	}
+}
+
+func TestFindPostsByTag(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	for _, content := range []string{"first #go post", "second #Go post", "not tagged"} {
+		post := models.Post{Title: content, Content: content, AuthorID: user.ID}
+		post.Prepare()
+		_, err = post.SavePost(server.DB)
+		if err != nil {
+			log.Fatalf("Cannot seed post %v\n", err)
+		}
+	}
+
+	posts, err := postInstance.FindPostsByTag(server.DB, "go", 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*posts), 2)
+	assert.Equal(t, (*posts)[0].Title, "second #Go post")
+}
+
+func TestPruneReadNotifications(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-time.Hour)
+	for _, readAt := range []*time.Time{nil, &old, &recent} {
+		err = server.DB.Create(&models.Notification{UserID: user.ID, ActorID: user.ID, PostID: 1, Kind: models.NotificationMention, ReadAt: readAt}).Error
+		if err != nil {
+			log.Fatalf("Cannot seed notification %v\n", err)
+		}
+	}
+
+	// A cancelled prune deletes nothing
+	notification := models.Notification{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pruned, err := notification.PruneRead(ctx, server.DB, now.Add(-24*time.Hour))
+	assert.Equal(t, errors.Is(err, context.Canceled), true)
+	assert.Equal(t, pruned, int64(0))
+
+	pruned, err = notification.PruneRead(context.Background(), server.DB, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Errorf("this is the error pruning the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, pruned, int64(1))
+
+	notifications, err := notification.FindUserNotifications(server.DB, user.ID, false, 10, 0)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 2)
+}
@@ -0,0 +1,211 @@
+package modeltests
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/migrations"
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestMigrationFiles(t *testing.T) {
+	reference, err := migrations.Load(migrations.Dialects[0])
+	if err != nil {
+		t.Fatalf("Cannot load the migrations: %v", err)
+	}
+	assert.NotEqual(t, len(reference), 0)
+	for _, dialect := range migrations.Dialects {
+		loaded, err := migrations.Load(dialect)
+		if err != nil {
+			t.Fatalf("Cannot load the %s migrations: %v", dialect, err)
+		}
+		// Every dialect has the same versions, each with both directions
+		assert.Equal(t, len(loaded), len(reference))
+		for i := range loaded {
+			assert.Equal(t, loaded[i].Version, reference[i].Version)
+			assert.Equal(t, loaded[i].Name, reference[i].Name)
+			assert.NotEqual(t, loaded[i].Up, "")
+			assert.NotEqual(t, loaded[i].Down, "")
+		}
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	_, err = seedOneUserAndPost()
+	if err != nil {
+		log.Fatalf("Error seeding table: %v\n", err)
+	}
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		t.Fatalf("Cannot read the migrations: %v", err)
+	}
+
+	pending, err := migrator.Pending()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, pending, 0)
+
+	// Applying again is a no-op that keeps the data
+	applied, err := migrator.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, applied, 0)
+	var count int
+	server.DB.Model(&models.Post{}).Count(&count)
+	assert.Equal(t, count, 1)
+
+	reverted, err := migrator.Down(len(migrator.Migrations()))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, reverted, len(migrator.Migrations()))
+	assert.Equal(t, server.DB.HasTable(&models.Post{}), false)
+	status, err := migrator.Status()
+	assert.Equal(t, err, nil)
+	for _, s := range status {
+		assert.Equal(t, s.AppliedAt == nil, true)
+	}
+
+	applied, err = migrator.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, applied, len(migrator.Migrations()))
+	assert.Equal(t, server.DB.HasTable(&models.Post{}), true)
+	status, err = migrator.Status()
+	assert.Equal(t, err, nil)
+	for _, s := range status {
+		assert.NotEqual(t, s.AppliedAt, nil)
+	}
+}
+
+// baselineUser and baselinePost are the models of the first release, which created
+// its tables with AutoMigrate
+type baselineUser struct {
+	ID        uint32    `gorm:"primary_key;auto_increment"`
+	Nickname  string    `gorm:"size:255;not null;unique"`
+	Email     string    `gorm:"size:100;not null;unique"`
+	Password  string    `gorm:"size:100;not null;"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselinePost struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	Title     string    `gorm:"size:255;not null;unique"`
+	Content   string    `gorm:"size:255;not null;"`
+	AuthorID  uint32    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+func (baselinePost) TableName() string { return "posts" }
+
+func TestMigrateBaselineDatabase(t *testing.T) {
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		t.Fatalf("Cannot read the migrations: %v", err)
+	}
+	_, err = migrator.Down(len(migrator.Migrations()))
+	if err != nil {
+		log.Fatalf("Cannot revert the migrations: %v\n", err)
+	}
+	err = server.DB.AutoMigrate(&baselineUser{}, &baselinePost{}).Error
+	if err != nil {
+		log.Fatalf("Cannot create the baseline tables: %v\n", err)
+	}
+	users := []baselineUser{
+		{Nickname: "Steven victor", Email: "steven@gmail.com", Password: "password"},
+		{Nickname: "Magu Frank", Email: "magu@gmail.com", Password: "password"},
+	}
+	for i := range users {
+		err = server.DB.Create(&users[i]).Error
+		if err != nil {
+			log.Fatalf("Cannot seed the baseline users: %v\n", err)
+		}
+	}
+	// The first release stored the titles and content HTML escaped
+	posts := []baselinePost{
+		{Title: "Hello, world", Content: "First", AuthorID: users[0].ID},
+		{Title: "Hello world!", Content: "Second", AuthorID: users[1].ID},
+		{Title: "Fish &amp; chips", Content: "&lt;b&gt;Fish&lt;/b&gt; &amp;amp; &#34;chips&#34; aren&#39;t", AuthorID: users[0].ID},
+	}
+	for i := range posts {
+		err = server.DB.Create(&posts[i]).Error
+		if err != nil {
+			log.Fatalf("Cannot seed the baseline posts: %v\n", err)
+		}
+	}
+
+	applied, err := migrator.Up()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, applied, len(migrator.Migrations()))
+
+	samples := []struct {
+		id      uint64
+		slug    string
+		title   string
+		content string
+	}{
+		{id: posts[0].ID, slug: "hello-world", title: "Hello, world", content: "First"},
+		{id: posts[1].ID, slug: "hello-world-2", title: "Hello world!", content: "Second"},
+		{id: posts[2].ID, slug: "fish-chips", title: "Fish & chips", content: `<b>Fish</b> &amp; "chips" aren't`},
+	}
+	for _, v := range samples {
+		post := models.Post{}
+		found, err := post.FindPostBySlug(server.DB, v.slug)
+		if err != nil {
+			t.Errorf("Cannot find the post %s: %v", v.slug, err)
+			continue
+		}
+		assert.Equal(t, found.ID, v.id)
+		assert.Equal(t, found.Title, v.title)
+		assert.Equal(t, found.Content, v.content)
+		assert.NotEqual(t, found.WordCount, 0)
+		assert.Equal(t, found.ReadingTimeMinutes, 1)
+		assert.Equal(t, found.IsPublished(), true)
+	}
+
+	// The upgraded schema takes the posts the first one refused: a title another
+	// author used, and content longer than 255 characters
+	newPost := models.Post{
+		Title:    "Hello, world",
+		Content:  strings.Repeat("Long content ", 30),
+		AuthorID: users[1].ID,
+	}
+	newPost.Prepare()
+	savedPost, err := newPost.SavePost(server.DB)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, savedPost.Slug, "hello-world-3")
+	assert.Equal(t, savedPost.Content, newPost.Content)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "postgres"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "postgres", "0007_existing.up.sql"), []byte(""), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := migrations.Create(dir, "Add Post Views")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(created), 2*len(migrations.Dialects))
+	assert.Equal(t, created[0], filepath.Join(dir, "postgres", "0008_add_post_views.up.sql"))
+	assert.Equal(t, created[1], filepath.Join(dir, "postgres", "0008_add_post_views.down.sql"))
+	for _, path := range created {
+		_, err = os.Stat(path)
+		assert.Equal(t, err, nil)
+	}
+
+	_, err = migrations.Create(dir, "!!")
+	assert.NotEqual(t, err, nil)
+}
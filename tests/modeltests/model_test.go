@@ -1,15 +1,15 @@
 package modeltests
 
 import (
-	"fmt"
 	"log"
 	"os"
 	"testing"
 
-	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"github.com/joho/godotenv"
+	"github.com/planutim/postgres-copy/api/config"
 	"github.com/planutim/postgres-copy/api/controllers"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/migrations"
 	"github.com/planutim/postgres-copy/api/models"
 )
 
@@ -28,40 +28,46 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+// Database connects to an in-memory SQLite database unless TestDbDriver names
+// another driver, configured by the other TestDb variables
 func Database() {
-	var err error
-
-	TestDbDriver := os.Getenv("TestDbDriver")
-
-	if TestDbDriver == "mysql" {
-		DBURL := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=local", os.Getenv("TestDbUser"), os.Getenv("TestDbPassword"), os.Getenv("TestDbHost"), os.Getenv("TestDbPort"), os.Getenv("TestDbName"))
-		server.DB, err = gorm.Open(TestDbDriver, DBURL)
-		if err != nil {
-			fmt.Printf("Cannot connect to %s databaase\n", TestDbDriver)
-			log.Fatal("This is the error: ", err)
-		} else {
-			fmt.Printf("We are connected to the %s database\n", TestDbDriver)
-		}
-	}
-	if TestDbDriver == "postgres" {
-		fmt.Println("BLABLABLAB")
-		DBURL := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s", os.Getenv("TestDbHost"), os.Getenv("TestDbPort"), os.Getenv("TestDbUser"), os.Getenv("TestDbName"), os.Getenv("TestDbPassword"))
-		server.DB, err = gorm.Open(TestDbDriver, DBURL)
-		if err != nil {
-			fmt.Printf("Cannot connect to %s databaase\n", TestDbDriver)
-			log.Fatal("This is the error: ", err)
-		} else {
-			fmt.Printf("We are connected to the %s database\n", TestDbDriver)
-		}
+	cfg := config.DBConfig{
+		Driver:       os.Getenv("TestDbDriver"),
+		Host:         os.Getenv("TestDbHost"),
+		Port:         os.Getenv("TestDbPort"),
+		User:         os.Getenv("TestDbUser"),
+		Password:     os.Getenv("TestDbPassword"),
+		Name:         os.Getenv("TestDbName"),
+		MaxOpenConns: 10,
+		MaxIdleConns: 10,
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = "sqlite3"
+		cfg.Name = ":memory:"
+	}
+	db, err := database.Open(cfg)
+	if err != nil {
+		log.Fatal("This is the error: ", err)
 	}
+	server.DB = db
 }
 
-func refreshUserTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}).Error
+// refreshSchema reverts every migration and applies them again, leaving empty tables
+func refreshSchema() error {
+	migrator, err := migrations.New(server.DB)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Down(len(migrator.Migrations()))
 	if err != nil {
 		return err
 	}
-	err = server.DB.AutoMigrate(&models.User{}).Error
+	_, err = migrator.Up()
+	return err
+}
+
+func refreshUserTable() error {
+	err := refreshSchema()
 	if err != nil {
 		return err
 	}
@@ -109,13 +115,7 @@ func seedUsers() error {
 }
 
 func refreshUserAndPostTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}, &models.Post{}).Error
-	if err != nil {
-		return err
-	}
-
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}).Error
-
+	err := refreshSchema()
 	if err != nil {
 		return err
 	}
@@ -197,3 +197,20 @@ func seedUsersAndPosts() ([]models.User, []models.Post, error) {
 	return users, posts, nil
 
 }
+
+func seedUsersAndFollows() ([]models.User, []models.Post, error) {
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		return []models.User{}, []models.Post{}, err
+	}
+	// the first user follows the second one
+	follow := models.Follow{
+		FollowerID:  users[0].ID,
+		FollowingID: users[1].ID,
+	}
+	err = server.DB.Model(&models.Follow{}).Create(&follow).Error
+	if err != nil {
+		return []models.User{}, []models.Post{}, err
+	}
+	return users, posts, nil
+}
@@ -2,8 +2,11 @@ package modeltests
 
 import (
 	"log"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/planutim/postgres-copy/api/apperrors"
 	"github.com/planutim/postgres-copy/api/models"
 	"gopkg.in/go-playground/assert.v1"
 )
@@ -73,6 +76,47 @@ func TestGetPostByID(t *testing.T) {
 	assert.Equal(t, foundPost.Content, post.Content)
 }
 
+func TestFindPostsByAuthors(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+	seeds := []models.Post{
+		{Title: "Second", Content: "About #go", AuthorID: users[0].ID},
+		{Title: "Third", Content: "Hello", AuthorID: users[0].ID},
+		{Title: "Draft", Content: "Not yet", AuthorID: users[0].ID, Status: models.PostStatusDraft},
+	}
+	for i := range seeds {
+		seeds[i].Prepare()
+		_, err = seeds[i].SavePost(server.DB)
+		if err != nil {
+			log.Fatalf("Cannot seed posts: %v\n", err)
+		}
+	}
+
+	// The limit applies to every author, drafts are left out
+	posts, err := postInstance.FindPostsByAuthors(server.DB, []uint32{users[0].ID, users[1].ID}, 2)
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	titles := []string{}
+	for _, p := range *posts {
+		titles = append(titles, p.Title)
+	}
+	assert.Equal(t, titles, []string{"Third", "Second", "Title 2"})
+	assert.Equal(t, (*posts)[1].Tags, []string{"go"})
+	assert.Equal(t, (*posts)[1].ContentHTML != "", true)
+
+	posts, err = postInstance.FindPostsByAuthors(server.DB, []uint32{}, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(*posts), 0)
+}
+
 func TestUpdateAPost(t *testing.T) {
 	err := refreshUserAndPostTable()
 	if err != nil {
@@ -99,6 +143,35 @@ func TestUpdateAPost(t *testing.T) {
 	assert.Equal(t, updatedPost.AuthorID, postUpdate.AuthorID)
 }
 
+func TestUpdateAPostKeepsReactions(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	post, err := seedOneUserAndPost()
+	if err != nil {
+		log.Fatalf("Error seeding table")
+	}
+	reaction := models.Reaction{PostID: post.ID, UserID: post.AuthorID, Type: models.ReactionLove}
+	_, err = reaction.SaveReaction(server.DB)
+	if err != nil {
+		log.Fatalf("Cannot seed the reaction: %v\n", err)
+	}
+
+	// Prepare knows nothing of the counters, the update reads them back
+	postUpdate := models.Post{Title: "Updated", Content: "Updated content", AuthorID: post.AuthorID}
+	postUpdate.Prepare()
+	postUpdate.ID = post.ID
+	updatedPost, err := postUpdate.UpdateAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, updatedPost.Reactions[models.ReactionLove], int64(1))
+	assert.Equal(t, updatedPost.Reactions[models.ReactionLike], int64(0))
+	assert.Equal(t, updatedPost.CreatedAt.Unix(), post.CreatedAt.Unix())
+}
+
 func TestDeleteAPost(t *testing.T) {
 	err := refreshUserAndPostTable()
 	if err != nil {
@@ -120,3 +193,206 @@ func TestDeleteAPost(t *testing.T) {
 	// Can be done this way too
 	assert.Equal(t, isDeleted, int64(1))
 }
+
+func TestSaveLongFormPost(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+
+	// 450 words, well past the old 255 character limit
+	content := "# Long read\n\n" + strings.Repeat("lorem ipsum dolor ", 150)
+	newPost := models.Post{
+		Title:    "A long read",
+		Content:  content,
+		AuthorID: user.ID,
+	}
+	newPost.Prepare()
+	err = newPost.Validate()
+	if err != nil {
+		t.Errorf("this is the error validating the post: %v\n", err)
+		return
+	}
+	savedPost, err := newPost.SavePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+
+	foundPost, err := postInstance.FindPostByID(server.DB, savedPost.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.Content, strings.TrimSpace(content))
+	assert.Equal(t, foundPost.WordCount, 452)
+	assert.Equal(t, foundPost.ReadingTimeMinutes, 3)
+	assert.Equal(t, strings.HasPrefix(foundPost.Excerpt, "Long read lorem ipsum"), true)
+	assert.Equal(t, strings.HasSuffix(foundPost.Excerpt, "…"), true)
+	assert.Equal(t, utf8.RuneCountInString(foundPost.Excerpt) <= 200, true)
+
+	// The stats are stored again when the content changes
+	postUpdate := models.Post{
+		Title:    "A long read",
+		Content:  "Not so long after all",
+		AuthorID: user.ID,
+	}
+	postUpdate.Prepare()
+	postUpdate.ID = savedPost.ID
+	_, err = postUpdate.UpdateAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	foundPost, err = postInstance.FindPostByID(server.DB, savedPost.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.WordCount, 5)
+	assert.Equal(t, foundPost.ReadingTimeMinutes, 1)
+}
+
+func TestSavePostKeepsTitleAndContentAsWritten(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+
+	newPost := models.Post{
+		Title:    `Tom & Jerry's "<show>"`,
+		Content:  "**Tom & Jerry** <3",
+		AuthorID: user.ID,
+	}
+	newPost.Prepare()
+	savedPost, err := newPost.SavePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedPost.Title, `Tom & Jerry's "<show>"`)
+	assert.Equal(t, savedPost.Slug, "tom-jerry-s-show")
+	assert.Equal(t, savedPost.Content, "**Tom & Jerry** <3")
+	assert.Equal(t, savedPost.ContentHTML, "<p><strong>Tom &amp; Jerry</strong> &lt;3</p>\n")
+}
+
+func TestValidatePostLength(t *testing.T) {
+	defaultMax := models.MaxContentLength
+	defer func() { models.MaxContentLength = defaultMax }()
+	models.MaxContentLength = 10
+
+	samples := []struct {
+		content      string
+		excerpt      string
+		errorMessage string
+	}{
+		{content: "short", errorMessage: ""},
+		{content: "ten runes!", errorMessage: ""},
+		{content: "eleven runes", errorMessage: "Content Too Long"},
+		{content: "short", excerpt: strings.Repeat("x", 501), errorMessage: "Excerpt Too Long"},
+	}
+	for _, v := range samples {
+		post := models.Post{Title: "Title", Content: v.content, Excerpt: v.excerpt, AuthorID: 1}
+		post.Prepare()
+		err := post.Validate()
+		if v.errorMessage == "" {
+			assert.Equal(t, err, nil)
+		} else {
+			assert.Equal(t, err.Error(), v.errorMessage)
+		}
+	}
+}
+
+func TestValidatePostFields(t *testing.T) {
+	samples := []struct {
+		post   models.Post
+		fields []string
+		kind   apperrors.Kind
+	}{
+		{post: models.Post{Title: "Title", Content: "Content", AuthorID: 1, Status: models.PostStatusDraft}},
+		{post: models.Post{Status: models.PostStatusPublished}, fields: []string{"title", "content", "author_id"}, kind: apperrors.Validation},
+		{post: models.Post{Title: "Title", AuthorID: 1, Status: "archived"}, fields: []string{"content", "status"}, kind: apperrors.Validation},
+	}
+	for _, v := range samples {
+		err := v.post.Validate()
+		assert.Equal(t, apperrors.KindOf(err), v.kind)
+		fields := []string{}
+		for _, f := range apperrors.Fields(err) {
+			fields = append(fields, f.Field)
+		}
+		if v.fields == nil {
+			assert.Equal(t, err, nil)
+			continue
+		}
+		assert.Equal(t, fields, v.fields)
+	}
+}
+
+func TestPostSlugs(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	post, err := seedOneUserAndPost()
+	if err != nil {
+		log.Fatalf("Error seeding table")
+	}
+	assert.Equal(t, post.Slug, "this-is-the-title-sam")
+
+	// Another post with the same title gets a suffix
+	other := models.Post{
+		Title:    post.Title,
+		Content:  "Other content",
+		AuthorID: post.AuthorID + 1,
+	}
+	err = server.DB.Model(&models.Post{}).Create(&other).Error
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, other.Slug, "this-is-the-title-sam-2")
+
+	// Changing the title moves the old slug to the history
+	post.Title = "A brand new title"
+	updatedPost, err := post.UpdateAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, updatedPost.Slug, "a-brand-new-title")
+
+	foundPost, err := post.FindPostBySlug(server.DB, "a-brand-new-title")
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.ID, post.ID)
+
+	current, err := post.FindCurrentSlug(server.DB, "this-is-the-title-sam")
+	if err != nil {
+		t.Errorf("this is the error getting the slug: %v\n", err)
+		return
+	}
+	assert.Equal(t, current, "a-brand-new-title")
+
+	// Old slugs stay reserved for the post that used them
+	third := models.Post{
+		Title:    "This is the title sam",
+		Content:  "Third content",
+		AuthorID: post.AuthorID,
+	}
+	err = server.DB.Model(&models.Post{}).Create(&third).Error
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, third.Slug, "this-is-the-title-sam-3")
+}
@@ -0,0 +1,96 @@
+package modeltests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/models"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var reactionInstance = models.Reaction{}
+
+func TestSaveReaction(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	post, err := seedOneUserAndPost()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+
+	newReaction := models.Reaction{
+		PostID: post.ID,
+		UserID: post.AuthorID,
+		Type:   models.ReactionLike,
+	}
+	savedReaction, err := newReaction.SaveReaction(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the reaction: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedReaction.PostID, post.ID)
+	assert.Equal(t, savedReaction.Type, models.ReactionLike)
+
+	// the same reaction twice is rejected by the unique index and the counter is left alone
+	duplicate := models.Reaction{
+		PostID: post.ID,
+		UserID: post.AuthorID,
+		Type:   models.ReactionLike,
+	}
+	_, err = duplicate.SaveReaction(server.DB)
+	assert.NotEqual(t, err, nil)
+
+	foundPost, err := postInstance.FindPostByID(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.Reactions[models.ReactionLike], int64(1))
+	assert.Equal(t, foundPost.Reactions[models.ReactionLove], int64(0))
+
+	mine, err := reactionInstance.FindUserReactions(server.DB, post.AuthorID, []uint64{post.ID})
+	if err != nil {
+		t.Errorf("this is the error getting the user reactions: %v\n", err)
+		return
+	}
+	assert.Equal(t, mine[post.ID], []string{models.ReactionLike})
+}
+
+func TestDeleteReaction(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table %v\n", err)
+	}
+	post, err := seedOneUserAndPost()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table %v\n", err)
+	}
+	reaction := models.Reaction{
+		PostID: post.ID,
+		UserID: post.AuthorID,
+		Type:   models.ReactionWow,
+	}
+	_, err = reaction.SaveReaction(server.DB)
+	if err != nil {
+		log.Fatalf("Error seeding reaction %v\n", err)
+	}
+
+	isDeleted, err := reactionInstance.DeleteReaction(server.DB, post.ID, post.AuthorID, models.ReactionWow)
+	if err != nil {
+		t.Errorf("this is the error deleting the reaction: %v\n", err)
+		return
+	}
+	assert.Equal(t, isDeleted, int64(1))
+
+	foundPost, err := postInstance.FindPostByID(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.Reactions[models.ReactionWow], int64(0))
+
+	_, err = reactionInstance.DeleteReaction(server.DB, post.ID, post.AuthorID, models.ReactionWow)
+	assert.Equal(t, err.Error(), "Reaction not found")
+}
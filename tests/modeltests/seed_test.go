@@ -0,0 +1,126 @@
+package modeltests
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/seed"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func countRecords() (int, int, int) {
+	var users, posts, tags int
+	server.DB.Model(&models.User{}).Count(&users)
+	server.DB.Model(&models.Post{}).Count(&posts)
+	server.DB.Model(&models.Tag{}).Count(&tags)
+	return users, posts, tags
+}
+
+func TestReadFixture(t *testing.T) {
+	fixture, err := seed.ReadFixture("../../api/seed/fixtures/demo.yaml")
+	if err != nil {
+		t.Fatalf("Cannot read the fixture: %v", err)
+	}
+	assert.Equal(t, len(fixture.Users), 2)
+	assert.Equal(t, len(fixture.Tags), 2)
+	assert.Equal(t, len(fixture.Posts), 3)
+	assert.Equal(t, fixture.Posts[2].Status, "draft")
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	err = os.WriteFile(path, []byte(`{"users": [{"nickname": "pet", "email": "pet@example.com", "password": "password"}]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture, err = seed.ReadFixture(path)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, fixture.Users[0].Email, "pet@example.com")
+
+	_, err = seed.ReadFixture(filepath.Join(t.TempDir(), "fixture.txt"))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	opts := seed.GenerateOptions{Users: 5, Posts: 20, Tags: 4, Seed: 42}
+	first := seed.Generate(opts)
+	second := seed.Generate(opts)
+	assert.Equal(t, first, second)
+	assert.Equal(t, len(first.Users), 5)
+	assert.Equal(t, len(first.Posts), 20)
+	assert.Equal(t, len(first.Tags), 4)
+
+	opts.Seed = 43
+	assert.NotEqual(t, seed.Generate(opts).Posts, first.Posts)
+}
+
+func TestApplyFixture(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	fixture, err := seed.ReadFixture("../../api/seed/fixtures/demo.yaml")
+	if err != nil {
+		t.Fatalf("Cannot read the fixture: %v", err)
+	}
+
+	result, err := seed.Apply(server.DB, fixture, false)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, result, seed.Result{UsersCreated: 2, TagsCreated: 2, PostsCreated: 3})
+	users, posts, tags := countRecords()
+	assert.Equal(t, []int{users, posts, tags}, []int{2, 3, 2})
+
+	// Mentions and hashtags of fixture content are linked like any other post
+	post := models.Post{}
+	err = server.DB.Where("title = ?", "Getting started with Go").Take(&post).Error
+	assert.Equal(t, err, nil)
+	foundPost, err := post.FindPostByID(server.DB, post.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, foundPost.Tags, []string{"golang"})
+	assert.Equal(t, foundPost.Mentions, []string{"martin"})
+
+	// Without upsert, loading twice conflicts
+	_, err = seed.Apply(server.DB, fixture, false)
+	assert.NotEqual(t, err, nil)
+
+	// With upsert, loading twice changes nothing
+	result, err = seed.Apply(server.DB, fixture, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, result, seed.Result{})
+	users, posts, tags = countRecords()
+	assert.Equal(t, []int{users, posts, tags}, []int{2, 3, 2})
+
+	// and updates what changed, by natural key
+	fixture.Users[0].Nickname = "steven2"
+	fixture.Posts[1].Content = "Start from the queries. #postgres #golang"
+	result, err = seed.Apply(server.DB, fixture, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, result, seed.Result{UsersUpdated: 1, PostsUpdated: 1})
+	users, posts, tags = countRecords()
+	assert.Equal(t, []int{users, posts, tags}, []int{2, 3, 2})
+
+	// Posts of unknown authors are refused
+	_, err = seed.Apply(server.DB, &seed.Fixture{Posts: []seed.PostFixture{{Author: "nobody@example.com", Title: "Lost", Content: "Lost"}}}, true)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestApplyGenerated(t *testing.T) {
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	fixture := seed.Generate(seed.GenerateOptions{Users: 10, Posts: 30, Tags: 5, Seed: 7})
+
+	result, err := seed.Apply(server.DB, fixture, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, result.UsersCreated, 10)
+	assert.Equal(t, result.PostsCreated, 30)
+	assert.Equal(t, result.TagsCreated, 5)
+
+	result, err = seed.Apply(server.DB, fixture, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, result, seed.Result{})
+	users, posts, tags := countRecords()
+	assert.Equal(t, []int{users, posts, tags}, []int{10, 30, 5})
+}
@@ -0,0 +1,91 @@
+package rendertests
+
+import (
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/render"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestMentions(t *testing.T) {
+	samples := []struct {
+		text     string
+		mentions []string
+	}{
+		{text: "hello @pet and @Magu.", mentions: []string{"pet", "Magu"}},
+		{text: "@pet @PET again", mentions: []string{"pet"}},
+		{text: "mail me at pet@gmail.com", mentions: []string{}},
+		{text: "@first.last-name, hi", mentions: []string{"first.last-name"}},
+		{text: "no mentions here", mentions: []string{}},
+	}
+	for _, v := range samples {
+		assert.Equal(t, render.Mentions(v.text), v.mentions)
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	samples := []struct {
+		text     string
+		hashtags []string
+	}{
+		{text: "#Go and #golang_tips", hashtags: []string{"go", "golang_tips"}},
+		{text: "#go #Go #GO", hashtags: []string{"go"}},
+		{text: "it&#39;s escaped, #1 is not a tag", hashtags: []string{}},
+		{text: "issue#12 and http://example.com/#anchor", hashtags: []string{}},
+	}
+	for _, v := range samples {
+		assert.Equal(t, render.Hashtags(v.text), v.hashtags)
+	}
+}
+
+func TestLinkify(t *testing.T) {
+	users := map[string]uint32{"pet": 1}
+
+	assert.Equal(t, render.Linkify("hi @Pet and @nobody #Go", users, "/api/v1"),
+		`hi <a href="/api/v1/users/1" class="mention">@Pet</a> and @nobody <a href="/api/v1/tags/go/posts" class="hashtag">#Go</a>`)
+	assert.Equal(t, render.Linkify("it&#39;s plain", users, "/api/v1"), "it&#39;s plain")
+}
+
+func TestHTML(t *testing.T) {
+	users := map[string]uint32{"pet": 1}
+	samples := []struct {
+		source string
+		html   string
+	}{
+		{
+			source: "**Tom & Jerry**",
+			html:   "<p><strong>Tom &amp; Jerry</strong></p>\n",
+		}, {
+			source: "hi @pet, see `@pet #code`",
+			html:   "<p>hi <a href=\"/api/v1/users/1\" class=\"mention\" rel=\"nofollow\">@pet</a>, see <code>@pet #code</code></p>\n",
+		}, {
+			// raw HTML and script links never make it to the output
+			source: "before <b onclick=alert(1)>b</b> [click](javascript:alert(1))",
+			html:   "<p>before b click</p>\n",
+		}, {
+			source: "<script>alert(1)</script>",
+			html:   "\n",
+		},
+	}
+	for _, v := range samples {
+		assert.Equal(t, render.HTML(v.source, users, "/api/v1"), v.html)
+	}
+}
+
+func TestText(t *testing.T) {
+	assert.Equal(t, render.Text("# Title\n\nSome *emphasis* & a [link](http://example.com)"), "Title Some emphasis & a link")
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, render.Truncate("short text", 20), "short text")
+	assert.Equal(t, render.Truncate("a sentence that is too long, really", 20), "a sentence that is…")
+	assert.Equal(t, render.Truncate("ünïcödé wörds everywhere", 12), "ünïcödé…")
+	assert.Equal(t, render.WordCount(" one two\tthree\n"), 3)
+}
+
+func TestReadingTime(t *testing.T) {
+	assert.Equal(t, render.ReadingTime(0), 0)
+	assert.Equal(t, render.ReadingTime(1), 1)
+	assert.Equal(t, render.ReadingTime(200), 1)
+	assert.Equal(t, render.ReadingTime(201), 2)
+}
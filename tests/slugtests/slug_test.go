@@ -0,0 +1,36 @@
+package slugtests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/planutim/postgres-copy/api/utils/slug"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestMake(t *testing.T) {
+	samples := []struct {
+		title string
+		slug  string
+	}{
+		{title: "Hello, World!", slug: "hello-world"},
+		{title: "  Go -- is   fun  ", slug: "go-is-fun"},
+		{title: "Crème brûlée à la française", slug: "creme-brulee-a-la-francaise"},
+		{title: "Straße und Ærø", slug: "strasse-und-aero"},
+		{title: "Привет мир", slug: "privet-mir"},
+		{title: "Їжак і йогурт", slug: "yizhak-i-yogurt"},
+		{title: "Καλημέρα", slug: "kalimera"},
+		{title: "日本語", slug: "post"},
+		{title: "", slug: "post"},
+	}
+	for _, v := range samples {
+		assert.Equal(t, slug.Make(v.title), v.slug)
+	}
+}
+
+func TestMakeLength(t *testing.T) {
+	s := slug.Make(strings.Repeat("word ", 40))
+	assert.Equal(t, len(s) <= slug.MaxLength, true)
+	assert.Equal(t, strings.HasSuffix(s, "-"), false)
+	assert.Equal(t, strings.HasPrefix(s, "word-word"), true)
+}
@@ -0,0 +1,116 @@
+package tracingtests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/planutim/postgres-copy/api/config"
+	"github.com/planutim/postgres-copy/api/database"
+	"github.com/planutim/postgres-copy/api/models"
+	"github.com/planutim/postgres-copy/api/requestid"
+	"github.com/planutim/postgres-copy/api/responses"
+	"github.com/planutim/postgres-copy/api/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func attributeOf(span tracetest.SpanStub, key attribute.Key) string {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	db, err := database.Open(config.DBConfig{Driver: "sqlite3", Name: ":memory:"})
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	defer db.Close()
+	err = db.Exec("CREATE TABLE users (id integer primary key, nickname varchar(255), email varchar(100), password varchar(100), created_at datetime, updated_at datetime)").Error
+	if err != nil {
+		t.Fatalf("this is the error: %v", err)
+	}
+	tracing.InstrumentDB(db)
+
+	router := mux.NewRouter()
+	router.Use(requestid.Middleware, tracing.Middleware)
+	router.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user := models.User{}
+		err := tracing.WithContext(db, r.Context()).Where("id = ?", mux.Vars(r)["id"]).Take(&user).Error
+		if err != nil {
+			responses.ERROR(w, http.StatusNotFound, errors.New("User not found"))
+			return
+		}
+		responses.JSON(w, http.StatusOK, user)
+	})
+
+	samples := []struct {
+		traceparent string
+		requestID   string
+	}{
+		{traceparent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", requestID: "bug-report-1"},
+		{},
+	}
+	for _, v := range samples {
+		exporter.Reset()
+		req, _ := http.NewRequest("GET", "/users/12", nil)
+		if v.traceparent != "" {
+			req.Header.Set("traceparent", v.traceparent)
+		}
+		req.Header.Set("X-Request-ID", v.requestID)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, 404)
+
+		// The request ID is sent back and quoted in the error
+		requestID := rr.Header().Get("X-Request-ID")
+		if v.requestID != "" {
+			assert.Equal(t, requestID, v.requestID)
+		}
+		assert.NotEqual(t, requestID, "")
+		body := map[string]interface{}{}
+		err = json.Unmarshal(rr.Body.Bytes(), &body)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, body["detail"], "User not found")
+		assert.Equal(t, body["request_id"], requestID)
+
+		spans := exporter.GetSpans()
+		assert.Equal(t, len(spans), 2)
+		query, server := spans[0], spans[1]
+		assert.Equal(t, server.Name, "GET /users/{id}")
+		assert.Equal(t, attributeOf(server, "http.route"), "/users/{id}")
+		assert.Equal(t, attributeOf(server, "http.response.status_code"), "404")
+		assert.Equal(t, attributeOf(server, "request.id"), requestID)
+		if v.traceparent != "" {
+			assert.Equal(t, server.SpanContext.TraceID().String(), "0af7651916cd43dd8448eb211c80319c")
+			assert.Equal(t, server.Parent.SpanID().String(), "b7ad6b7169203331")
+		}
+		assert.Equal(t, strings.Contains(rr.Header().Get("traceparent"), server.SpanContext.TraceID().String()), true)
+
+		// The query is a child of the request span, and not finding a record is no error
+		assert.Equal(t, query.Name, "query users")
+		assert.Equal(t, query.Parent.SpanID(), server.SpanContext.SpanID())
+		assert.Equal(t, attributeOf(query, "db.system"), "sqlite3")
+		assert.Equal(t, strings.Contains(attributeOf(query, "db.query.text"), "WHERE (id = ?)"), true)
+		assert.Equal(t, query.Status.Description, "")
+	}
+}
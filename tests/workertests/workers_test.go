@@ -0,0 +1,64 @@
+package workertests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/planutim/postgres-copy/api/workers"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestGroup(t *testing.T) {
+	var runs int32
+	ok := &workers.Worker{
+		Name:     "ok",
+		Interval: time.Millisecond,
+		Job: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	}
+	failing := &workers.Worker{
+		Name:     "failing",
+		Interval: time.Hour,
+		Job: func(ctx context.Context) error {
+			return errors.New("database is gone")
+		},
+	}
+	// blocking only returns once it is told to stop
+	blocking := &workers.Worker{
+		Name:     "blocking",
+		Interval: time.Hour,
+		Job: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	group := workers.NewGroup(ok, failing, blocking)
+	group.Start()
+
+	deadline := time.Now().Add(time.Second)
+	for (atomic.LoadInt32(&runs) < 3 || group.Health()[1].LastRun == nil) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, atomic.LoadInt32(&runs) >= 3, true)
+
+	health := group.Health()
+	assert.Equal(t, len(health), 3)
+	assert.Equal(t, health[0].Name, "ok")
+	assert.Equal(t, health[0].Healthy(), true)
+	assert.Equal(t, health[1].LastError, "database is gone")
+	assert.Equal(t, health[1].Healthy(), false)
+	assert.Equal(t, health[2].Running, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := group.Stop(ctx)
+	assert.Equal(t, err, nil)
+	for _, h := range group.Health() {
+		assert.Equal(t, h.Running, false)
+	}
+}